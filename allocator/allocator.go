@@ -0,0 +1,169 @@
+package allocator
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+)
+
+var (
+	ErrNotEnoughSeats  = errors.New("not enough seats are available")
+	ErrNoAdjacentSeats = errors.New("no adjacent seats are available for the whole group")
+)
+
+// Weights of the seat score, lower scores are better seats.
+// A better section always wins over a closer row, a closer row over a more central seat.
+const (
+	sectionWeight = 1000.0
+	rowWeight     = 1.0
+	// Applied to the distance from the middle of the row, from 0 (middle) to 1 (aisle).
+	centerWeight = 0.5
+)
+
+// Seat is a seat of the venue as the allocator sees it.
+type Seat struct {
+	ID        int
+	SectionID int
+	// 1 is the best section, zero leaves the section unranked behind every ranked one.
+	SectionPriority int
+	Row             string
+	// Rows counted from the stage, 1 being the front row.
+	RowRank int
+	// Seats next to each other have consecutive numbers.
+	Number    int
+	Available bool
+}
+
+type Request struct {
+	Quantity int
+	// The buyer accepts seats that are not all next to each other
+	// when no row has room for the whole group.
+	AllowSplit bool
+}
+
+type rowKey struct {
+	sectionID int
+	row       string
+}
+
+// A run of adjacent seats in a single row.
+type block struct {
+	seats []Seat
+	score float64
+}
+
+// Allocate picks the best available seats for the request. The group gets the best block
+// of adjacent seats in one row. Without one it fails with ErrNoAdjacentSeats, unless
+// the buyer allows a split, in which case the group is kept in as few blocks as possible.
+//
+// Unavailable seats are still used to find the middle of their row.
+func Allocate(seats []Seat, req Request) ([]Seat, error) {
+	if req.Quantity <= 0 {
+		return nil, nil
+	}
+	rows := map[rowKey][]Seat{}
+	available := 0
+	for _, s := range seats {
+		k := rowKey{s.SectionID, s.Row}
+		rows[k] = append(rows[k], s)
+		if s.Available {
+			available++
+		}
+	}
+	if available < req.Quantity {
+		return nil, ErrNotEnoughSeats
+	}
+	scores := map[int]float64{}
+	for k, row := range rows {
+		slices.SortFunc(row, func(a, b Seat) int { return cmp.Compare(a.Number, b.Number) })
+		first, last := row[0].Number, row[len(row)-1].Number
+		middle := float64(first+last) / 2
+		halfWidth := max(float64(last-first)/2, 1)
+		for _, s := range row {
+			scores[s.ID] = score(s, math.Abs(float64(s.Number)-middle)/halfWidth)
+		}
+		rows[k] = row
+	}
+	if b, ok := bestBlock(rows, scores, req.Quantity, nil); ok {
+		return b.seats, nil
+	}
+	if !req.AllowSplit {
+		return nil, ErrNoAdjacentSeats
+	}
+	// Take the largest block still available, best first, until the whole group is seated.
+	taken := map[int]bool{}
+	allocated := make([]Seat, 0, req.Quantity)
+	for remaining := req.Quantity; remaining > 0; {
+		for size := remaining; size > 0; size-- {
+			b, ok := bestBlock(rows, scores, size, taken)
+			if !ok {
+				continue
+			}
+			for _, s := range b.seats {
+				taken[s.ID] = true
+			}
+			allocated = append(allocated, b.seats...)
+			remaining -= size
+			break
+		}
+	}
+	return allocated, nil
+}
+
+func score(s Seat, offCenter float64) float64 {
+	priority := float64(s.SectionPriority)
+	if s.SectionPriority == 0 {
+		// Unranked sections come after every ranked one.
+		priority = math.MaxInt32
+	}
+	return priority*sectionWeight + float64(s.RowRank)*rowWeight + offCenter*centerWeight
+}
+
+// The lowest scoring block of size adjacent available seats, skipping the taken ones.
+// Ties go to the front row, then the lower section and seat numbers, so the result is stable.
+func bestBlock(rows map[rowKey][]Seat, scores map[int]float64, size int, taken map[int]bool) (block, bool) {
+	var best block
+	found := false
+	for _, row := range rows {
+		for i := 0; i+size <= len(row); i++ {
+			window := row[i : i+size]
+			if !adjacent(window, taken) {
+				continue
+			}
+			b := block{seats: window}
+			for _, s := range window {
+				b.score += scores[s.ID]
+			}
+			if !found || better(b, best) {
+				best, found = b, true
+			}
+		}
+	}
+	return best, found
+}
+
+func adjacent(window []Seat, taken map[int]bool) bool {
+	for i, s := range window {
+		if !s.Available || taken[s.ID] {
+			return false
+		}
+		if i > 0 && s.Number != window[i-1].Number+1 {
+			return false
+		}
+	}
+	return true
+}
+
+func better(a, b block) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	x, y := a.seats[0], b.seats[0]
+	return cmp.Or(
+		cmp.Compare(x.RowRank, y.RowRank),
+		cmp.Compare(x.SectionID, y.SectionID),
+		cmp.Compare(x.Row, y.Row),
+		cmp.Compare(x.Number, y.Number),
+	) < 0
+}
@@ -0,0 +1,135 @@
+package allocator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// Seats from through to of a row, the taken numbers unavailable.
+// IDs read as section, row rank and number: 1203 is seat 3 of row rank 2 in section 1.
+func row(sectionID int, name string, rank int, from int, to int, taken ...int) []Seat {
+	var seats []Seat
+	for n := from; n <= to; n++ {
+		seats = append(seats, Seat{
+			ID:        sectionID*1000 + rank*100 + n,
+			SectionID: sectionID,
+			Row:       name,
+			RowRank:   rank,
+			Number:    n,
+			Available: !slices.Contains(taken, n),
+		})
+	}
+	return seats
+}
+
+// The seats moved into a section of the given priority.
+func ranked(priority int, seats []Seat) []Seat {
+	for i := range seats {
+		seats[i].SectionPriority = priority
+	}
+	return seats
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name  string
+		seats []Seat
+		req   Request
+		want  []int
+		err   error
+	}{
+		{
+			name:  "keeps the group adjacent near the middle of the front row",
+			seats: slices.Concat(row(1, "A", 1, 1, 10, 5), row(1, "B", 2, 1, 10)),
+			req:   Request{Quantity: 3},
+			want:  []int{1106, 1107, 1108},
+		},
+		{
+			name:  "moves back a row when the front row is full",
+			seats: slices.Concat(row(1, "A", 1, 1, 4, 1, 2, 3, 4), row(1, "B", 2, 1, 4)),
+			req:   Request{Quantity: 2},
+			want:  []int{1202, 1203},
+		},
+		{
+			name:  "moves back a row when the front row has no adjacent seats",
+			seats: slices.Concat(row(1, "A", 1, 1, 6, 2, 4), row(1, "B", 2, 1, 6)),
+			req:   Request{Quantity: 3},
+			want:  []int{1202, 1203, 1204},
+		},
+		{
+			name:  "fails without adjacent seats when the buyer does not allow a split",
+			seats: row(1, "A", 1, 1, 6, 2, 4, 6),
+			req:   Request{Quantity: 2},
+			err:   ErrNoAdjacentSeats,
+		},
+		{
+			name:  "splits into single seats, best first",
+			seats: row(1, "A", 1, 1, 6, 2, 4, 6),
+			req:   Request{Quantity: 2, AllowSplit: true},
+			want:  []int{1103, 1105},
+		},
+		{
+			name:  "splits into the largest blocks available",
+			seats: row(1, "A", 1, 1, 6, 3),
+			req:   Request{Quantity: 4, AllowSplit: true},
+			want:  []int{1104, 1105, 1106, 1102},
+		},
+		{
+			name:  "prefers the section with the better priority over a closer row",
+			seats: slices.Concat(ranked(2, row(1, "A", 1, 1, 4)), ranked(1, row(2, "J", 9, 1, 4))),
+			req:   Request{Quantity: 2},
+			want:  []int{2902, 2903},
+		},
+		{
+			name:  "puts unranked sections behind ranked ones",
+			seats: slices.Concat(row(1, "A", 1, 1, 4), ranked(3, row(2, "A", 1, 1, 4))),
+			req:   Request{Quantity: 2},
+			want:  []int{2102, 2103},
+		},
+		{
+			name:  "moves to the next section when the best one has no adjacent seats",
+			seats: slices.Concat(ranked(1, row(1, "A", 1, 1, 4, 2)), ranked(2, row(2, "A", 1, 1, 4))),
+			req:   Request{Quantity: 3},
+			want:  []int{2101, 2102, 2103},
+		},
+		{
+			name:  "breaks ties by the lower section",
+			seats: slices.Concat(row(2, "A", 1, 1, 4), row(1, "A", 1, 1, 4)),
+			req:   Request{Quantity: 2},
+			want:  []int{1102, 1103},
+		},
+		{
+			name:  "breaks ties by the lower seat number",
+			seats: row(1, "A", 1, 1, 4),
+			req:   Request{Quantity: 1},
+			want:  []int{1102},
+		},
+		{
+			name:  "fails when fewer seats are available than requested",
+			seats: row(1, "A", 1, 1, 4, 1),
+			req:   Request{Quantity: 4, AllowSplit: true},
+			err:   ErrNotEnoughSeats,
+		},
+		{
+			name:  "allocates nothing for no tickets",
+			seats: row(1, "A", 1, 1, 4),
+			req:   Request{Quantity: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.seats, tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			var ids []int
+			for _, s := range got {
+				ids = append(ids, s.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("seats = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
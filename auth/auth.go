@@ -0,0 +1,143 @@
+// Package auth issues and checks the credentials requests are made with:
+// JWTs signed with a local key for people and API keys for service accounts.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role = string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleOrganizer Role = "organizer"
+	RoleGateStaff Role = "gate_staff"
+	RoleCustomer  Role = "customer"
+)
+
+var StaffRoles = []Role{RoleAdmin, RoleOrganizer, RoleGateStaff}
+
+func IsStaffRole(role Role) bool {
+	return slices.Contains(StaffRoles, role)
+}
+
+// What kind of account a principal stands for.
+type Kind = string
+
+const (
+	KindCustomer Kind = "customer"
+	KindStaff    Kind = "staff"
+	KindAPIKey   Kind = "api_key"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Principal is whoever a request is made by.
+type Principal struct {
+	Kind Kind
+	// ID of the customer, staff member or API key.
+	ID   int
+	Role Role
+	// The organizer an organizer account acts for.
+	OrganizerID int
+}
+
+// Recorded as the actor of the changes the principal makes.
+func (p Principal) String() string {
+	return p.Kind + ":" + strconv.Itoa(p.ID)
+}
+
+type claims struct {
+	Kind        Kind `json:"kind"`
+	Role        Role `json:"role"`
+	OrganizerID int  `json:"org,omitempty"`
+	jwt.RegisteredClaims
+}
+
+const issuer = "gate-keeper"
+
+// Issuer signs and parses HS256 JWTs with a local key.
+type Issuer struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewIssuer(key []byte, ttl time.Duration) *Issuer {
+	return &Issuer{key: key, ttl: ttl}
+}
+
+// Issue returns a token for the principal and when it expires.
+func (i *Issuer) Issue(p Principal) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Kind:        p.Kind,
+		Role:        p.Role,
+		OrganizerID: p.OrganizerID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(p.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	signed, err := token.SignedString(i.key)
+	return signed, expiresAt, err
+}
+
+func (i *Issuer) Parse(token string) (Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return i.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	p := Principal{Kind: c.Kind, ID: id, Role: c.Role, OrganizerID: c.OrganizerID}
+	if p.Role == RoleOrganizer && p.OrganizerID == 0 {
+		return Principal{}, ErrInvalidToken
+	}
+	return p, nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash string, password string) bool {
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewAPIKey returns a random key, shown to its owner once and only stored hashed.
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "gk_" + hex.EncodeToString(b), nil
+}
+
+// API keys are long and random, a fast hash is enough to look them up.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/hendrywilliam/gate-keeper/auth"
+)
+
+// Reads the HMAC key from JWT_SIGNING_KEY (standard base64, at least 32 bytes) and the token lifetime from JWT_TTL.
+// Without a key a throwaway one is generated and everybody has to sign in again after a restart.
+func NewTokenIssuer() (*auth.Issuer, error) {
+	ttl, err := DurationEnv("JWT_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	v := os.Getenv("JWT_SIGNING_KEY")
+	if v == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		slog.Warn("JWT_SIGNING_KEY is not set, using a throwaway signing key.")
+		return auth.NewIssuer(key, ttl), nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_SIGNING_KEY: %w", err)
+	}
+	if len(key) < 32 {
+		return nil, errors.New("invalid JWT_SIGNING_KEY: key must be at least 32 bytes")
+	}
+	return auth.NewIssuer(key, ttl), nil
+}
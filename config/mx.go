@@ -1,17 +1,167 @@
 package config
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	mathRand "math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/go-redsync/redsync/v4"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
 	redisClient "github.com/redis/go-redis/v9"
 )
 
-func NewMutex() *redsync.Mutex {
-	redis := redisClient.NewClient(&redisClient.Options{
-		Addr: "localhost:6379",
+func NewRedis(addr string) *redisClient.Client {
+	return redisClient.NewClient(&redisClient.Options{
+		Addr: addr,
 	})
+}
+
+// LockPolicy describes how long a request is willing to wait for a lock.
+type LockPolicy struct {
+	// Total time spent trying to acquire the lock.
+	MaxWait time.Duration
+	// Delay before the first retry, doubled on every retry up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// Random extra delay as a fraction of the current delay (0.2 = up to 20%).
+	Jitter float64
+	// Serve waiters in arrival order instead of whoever retries first.
+	Fair bool
+	// Hint returned to clients that did not get the lock.
+	RetryAfter time.Duration
+}
+
+// Reads the LOCK_* environment variables, falling back to sane defaults.
+func NewLockPolicy() (LockPolicy, error) {
+	var err error
+	p := LockPolicy{}
+	if p.MaxWait, err = DurationEnv("LOCK_MAX_WAIT", 3*time.Second); err != nil {
+		return p, err
+	}
+	if p.RetryDelay, err = DurationEnv("LOCK_RETRY_DELAY", 50*time.Millisecond); err != nil {
+		return p, err
+	}
+	if p.MaxRetryDelay, err = DurationEnv("LOCK_MAX_RETRY_DELAY", 500*time.Millisecond); err != nil {
+		return p, err
+	}
+	if p.RetryAfter, err = DurationEnv("LOCK_RETRY_AFTER", time.Second); err != nil {
+		return p, err
+	}
+	if v := os.Getenv("LOCK_JITTER"); v != "" {
+		if p.Jitter, err = strconv.ParseFloat(v, 64); err != nil {
+			return p, fmt.Errorf("invalid LOCK_JITTER: %w", err)
+		}
+	} else {
+		p.Jitter = 0.2
+	}
+	if v := os.Getenv("LOCK_FAIR"); v != "" {
+		if p.Fair, err = strconv.ParseBool(v); err != nil {
+			return p, fmt.Errorf("invalid LOCK_FAIR: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Exponential backoff with jitter for the given retry attempt (starting at 1).
+func (p LockPolicy) Delay(attempt int) time.Duration {
+	delay := p.RetryDelay
+	for i := 1; i < attempt && delay < p.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxRetryDelay)
+	if p.Jitter > 0 {
+		delay += time.Duration(mathRand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// Locker hands out distributed locks scoped to a single concert (or seat),
+// so purchases for different concerts do not block each other.
+type Locker struct {
+	rs     *redsync.Redsync
+	redis  *redisClient.Client
+	Policy LockPolicy
+}
+
+func NewLocker(redis *redisClient.Client, policy LockPolicy) *Locker {
 	pool := goredis.NewPool(redis)
-	rs := redsync.New(pool)
-	lock := rs.NewMutex("okegas")
-	return lock
+	return &Locker{
+		rs:     redsync.New(pool),
+		redis:  redis,
+		Policy: policy,
+	}
+}
+
+func (l *Locker) newMutex(name string) *redsync.Mutex {
+	return l.rs.NewMutex(
+		name,
+		// Bounded by MaxWait in Acquire, not by the number of tries.
+		redsync.WithTries(math.MaxInt32),
+		redsync.WithRetryDelayFunc(l.Policy.Delay),
+	)
+}
+
+func (l *Locker) ConcertMutex(concertID int) *redsync.Mutex {
+	return l.newMutex(fmt.Sprintf("lock:concert:%d", concertID))
+}
+
+// Guards a single seat of a concert, so two buyers cannot pick the same seat at once.
+func (l *Locker) SeatMutex(concertID int, seatID int) *redsync.Mutex {
+	return l.newMutex(fmt.Sprintf("lock:concert:%d:seat:%d", concertID, seatID))
+}
+
+// Acquire blocks until the lock is granted or the policy's MaxWait has passed.
+func (l *Locker) Acquire(ctx context.Context, mx *redsync.Mutex) error {
+	ctx, cancel := context.WithTimeout(ctx, l.Policy.MaxWait)
+	defer cancel()
+	if !l.Policy.Fair {
+		return mx.LockContext(ctx)
+	}
+	return l.acquireFair(ctx, mx)
+}
+
+// Waiters line up in a sorted set scored by arrival time,
+// only the head of the line is allowed to try the lock.
+func (l *Locker) acquireFair(ctx context.Context, mx *redsync.Mutex) error {
+	waiters := mx.Name() + ":waiters"
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	member := hex.EncodeToString(b)
+	now := time.Now()
+	if err := l.redis.ZAdd(ctx, waiters, redisClient.Z{
+		Score:  float64(now.UnixNano()),
+		Member: member,
+	}).Err(); err != nil {
+		return err
+	}
+	defer l.redis.ZRem(context.Background(), waiters, member)
+	for attempt := 1; ; attempt++ {
+		// Waiters that crashed never remove themselves, nobody waits longer than MaxWait.
+		stale := time.Now().Add(-2 * l.Policy.MaxWait).UnixNano()
+		l.redis.ZRemRangeByScore(ctx, waiters, "-inf", strconv.FormatInt(stale, 10))
+		rank, err := l.redis.ZRank(ctx, waiters, member).Result()
+		if err != nil {
+			return err
+		}
+		if rank == 0 {
+			if err := mx.TryLockContext(ctx); err == nil {
+				return nil
+			}
+		}
+		timer := time.NewTimer(l.Policy.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return redsync.ErrFailed
+		case <-timer.C:
+		}
+	}
 }
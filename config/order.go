@@ -0,0 +1,22 @@
+package config
+
+// OrderLimits caps how many tickets can be bought at once and in total.
+type OrderLimits struct {
+	// Tickets in a single order.
+	PerOrder int
+	// Tickets a customer may hold for a single concert, across orders.
+	PerCustomer int
+}
+
+// Reads ORDER_MAX_TICKETS and CUSTOMER_MAX_TICKETS.
+func NewOrderLimits() (OrderLimits, error) {
+	var err error
+	l := OrderLimits{}
+	if l.PerOrder, err = IntEnv("ORDER_MAX_TICKETS", 6); err != nil {
+		return l, err
+	}
+	if l.PerCustomer, err = IntEnv("CUSTOMER_MAX_TICKETS", 10); err != nil {
+		return l, err
+	}
+	return l, nil
+}
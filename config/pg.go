@@ -2,11 +2,15 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Time zone of database sessions, dates shown to people use it as well.
+const TimeZone = "Asia/Jakarta"
+
 func NewPg(ctx context.Context, url string) (*pgxpool.Pool, error) {
 	conn, err := pgxpool.New(ctx, url)
 	if err != nil {
@@ -17,10 +21,10 @@ func NewPg(ctx context.Context, url string) (*pgxpool.Pool, error) {
 		return nil, err
 	}
 	slog.Info("db connection established.")
-	_, err = conn.Exec(ctx, "SET TIMEZONE TO 'Asia/Jakarta';")
+	_, err = conn.Exec(ctx, fmt.Sprintf("SET TIMEZONE TO '%s';", TimeZone))
 	if err != nil {
 		return nil, err
 	}
-	slog.Info("db timezone set to Asia/Jakarta.")
+	slog.Info(fmt.Sprintf("db timezone set to %s.", TimeZone))
 	return conn, nil
 }
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RefundPolicy decides how much of the ticket price goes back to the customer.
+type RefundPolicy struct {
+	// Share of the price refunded, from 0 to 100.
+	Percentage float64
+	// Cancellations closer than this to the concert date are not refunded.
+	Cutoff time.Duration
+}
+
+// Reads REFUND_PERCENTAGE and REFUND_CUTOFF.
+func NewRefundPolicy() (RefundPolicy, error) {
+	var err error
+	p := RefundPolicy{Percentage: 100}
+	if v := os.Getenv("REFUND_PERCENTAGE"); v != "" {
+		if p.Percentage, err = strconv.ParseFloat(v, 64); err != nil {
+			return p, fmt.Errorf("invalid REFUND_PERCENTAGE: %w", err)
+		}
+		if p.Percentage < 0 || p.Percentage > 100 {
+			return p, fmt.Errorf("invalid REFUND_PERCENTAGE: %v is not within 0-100", p.Percentage)
+		}
+	}
+	if p.Cutoff, err = DurationEnv("REFUND_CUTOFF", 24*time.Hour); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// Refunded share (0-100) for a cancellation at now of a concert held at concertDate.
+// A zero concertDate stands for a concert without a date yet, which has no cutoff.
+func (p RefundPolicy) PercentageAt(concertDate time.Time, now time.Time) float64 {
+	if !concertDate.IsZero() && now.Add(p.Cutoff).After(concertDate) {
+		return 0
+	}
+	return p.Percentage
+}
+
+// Refunded amount, rounded to cents.
+func (p RefundPolicy) Amount(price float64, concertDate time.Time, now time.Time) float64 {
+	return math.Round(price*p.PercentageAt(concertDate, now)) / 100
+}
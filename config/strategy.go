@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// PurchaseStrategy decides how overselling is prevented when buying a ticket.
+type PurchaseStrategy string
+
+const (
+	// Serialize purchases per concert with a redsync lock, on top of the conditional decrement.
+	PurchaseStrategyRedsync PurchaseStrategy = "redsync"
+	// Rely on a conditional decrement in Postgres only, no Redis involved.
+	PurchaseStrategyDB PurchaseStrategy = "db"
+)
+
+// Reads PURCHASE_STRATEGY, defaults to redsync.
+func NewPurchaseStrategy() (PurchaseStrategy, error) {
+	s := PurchaseStrategy(os.Getenv("PURCHASE_STRATEGY"))
+	switch s {
+	case "":
+		return PurchaseStrategyRedsync, nil
+	case PurchaseStrategyRedsync, PurchaseStrategyDB:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown purchase strategy: %s", s)
+	}
+}
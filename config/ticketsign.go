@@ -0,0 +1,35 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/hendrywilliam/gate-keeper/ticketsign"
+)
+
+// Reads the ed25519 seed from TICKET_SIGNING_KEY (standard base64, 32 bytes).
+// Without it a throwaway key is generated and tokens stop verifying after a restart.
+func NewTicketSigner() (*ticketsign.Signer, error) {
+	v := os.Getenv("TICKET_SIGNING_KEY")
+	if v == "" {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		slog.Warn("TICKET_SIGNING_KEY is not set, using a throwaway ticket signing key.")
+		return ticketsign.NewSigner(key), nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TICKET_SIGNING_KEY: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid TICKET_SIGNING_KEY: seed must be 32 bytes")
+	}
+	return ticketsign.NewSigner(ed25519.NewKeyFromSeed(seed)), nil
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+type ArtistController struct {
+	Q   *queries.Queries
+	Log *slog.Logger
+}
+
+func NewArtistController(q *queries.Queries, log *slog.Logger) *ArtistController {
+	return &ArtistController{
+		Q:   q,
+		Log: log,
+	}
+}
+
+func (ac *ArtistController) CreateArtist(c fiber.Ctx) error {
+	var req dto.CreateArtistRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "name is required",
+		})
+	}
+	artist, err := ac.Q.Artist.CreateArtist(c.Context(), queries.CreateArtistArgs{
+		Name:  req.Name,
+		Genre: strings.TrimSpace(req.Genre),
+	})
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("artist created", "artist", artist)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "artist created.",
+		"data":    artist,
+	})
+}
+
+func (ac *ArtistController) ListArtists(c fiber.Ctx) error {
+	artists, err := ac.Q.Artist.ListArtists(c.Context())
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "artists obtained.",
+		"data":    artists,
+	})
+}
+
+func (ac *ArtistController) UpdateArtist(c fiber.Ctx) error {
+	var req dto.UpdateArtistRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	artist, err := ac.Q.Artist.UpdateArtist(c.Context(), queries.UpdateArtistArgs{
+		ID:    req.ID,
+		Name:  strings.TrimSpace(req.Name),
+		Genre: strings.TrimSpace(req.Genre),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no artist with the specified ID was found",
+			})
+		}
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("artist updated", "artist", artist)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "artist updated.",
+		"data":    artist,
+	})
+}
+
+func (ac *ArtistController) DeleteArtist(c fiber.Ctx) error {
+	var req dto.DeleteArtistRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	artist, err := ac.Q.Artist.DeleteArtist(c.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no artist with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrArtistInUse) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "artist still has concerts.",
+			})
+		}
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("artist deleted", "artist", artist)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "artist deleted.",
+		"data":    artist,
+	})
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/auth"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+const minPasswordLength = 8
+
+type AuthController struct {
+	Q      *queries.Queries
+	Log    *slog.Logger
+	Issuer *auth.Issuer
+}
+
+func NewAuthController(q *queries.Queries, log *slog.Logger, issuer *auth.Issuer) *AuthController {
+	return &AuthController{
+		Q:      q,
+		Log:    log,
+		Issuer: issuer,
+	}
+}
+
+// EnsureAdmin creates the first admin so there is somebody to create the rest of the staff.
+func (ac *AuthController) EnsureAdmin(ctx context.Context, email string, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	staff, err := ac.Q.Staff.CreateStaff(ctx, queries.CreateStaffArgs{
+		Email:        normalizeEmail(email),
+		Role:         auth.RoleAdmin,
+		PasswordHash: hash,
+	})
+	if errors.Is(err, queries.ErrStaffExists) {
+		return nil
+	}
+	if err == nil {
+		ac.Log.Info("admin created", "staff", staff)
+	}
+	return err
+}
+
+// IssueCustomerToken signs a customer in.
+func (ac *AuthController) IssueCustomerToken(c fiber.Ctx) error {
+	var req dto.IssueTokenRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	customer, err := ac.Q.Customer.GetCustomerByEmail(c.Context(), normalizeEmail(req.Email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	// Unknown emails and wrong passwords look the same.
+	if err != nil || !auth.CheckPassword(customer.PasswordHash, req.Password) {
+		return invalidCredentials(c)
+	}
+	return ac.issueToken(c, auth.Principal{Kind: auth.KindCustomer, ID: customer.ID, Role: auth.RoleCustomer})
+}
+
+// IssueStaffToken signs a staff member in with the role of their account.
+func (ac *AuthController) IssueStaffToken(c fiber.Ctx) error {
+	var req dto.IssueTokenRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	staff, err := ac.Q.Staff.GetStaffByEmail(c.Context(), normalizeEmail(req.Email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if err != nil || !auth.CheckPassword(staff.PasswordHash, req.Password) {
+		return invalidCredentials(c)
+	}
+	return ac.issueToken(c, auth.Principal{
+		Kind:        auth.KindStaff,
+		ID:          staff.ID,
+		Role:        staff.Role,
+		OrganizerID: staff.OrganizerID,
+	})
+}
+
+func (ac *AuthController) issueToken(c fiber.Ctx, principal auth.Principal) error {
+	token, expiresAt, err := ac.Issuer.Issue(principal)
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "signed in.",
+		"data": fiber.Map{
+			"token_type": "Bearer",
+			"token":      token,
+			"role":       principal.Role,
+			"expires_at": expiresAt.Unix(),
+		},
+	})
+}
+
+func invalidCredentials(c fiber.Ctx) error {
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+		"code":    http.StatusUnauthorized,
+		"message": "invalid email or password.",
+	})
+}
+
+func (ac *AuthController) CreateStaff(c fiber.Ctx) error {
+	var req dto.CreateStaffRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Email = normalizeEmail(req.Email)
+	if req.Email == "" || len(req.Password) < minPasswordLength || !auth.IsStaffRole(req.Role) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "email, a password of at least 8 characters and a role of admin, organizer or gate_staff are required",
+		})
+	}
+	if err := ac.checkOrganizer(c.Context(), req.Role, req.OrganizerID); err != nil {
+		return ac.organizerError(c, err)
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	staff, err := ac.Q.Staff.CreateStaff(c.Context(), queries.CreateStaffArgs{
+		Email:        req.Email,
+		Name:         strings.TrimSpace(req.Name),
+		Role:         req.Role,
+		OrganizerID:  req.OrganizerID,
+		PasswordHash: hash,
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrStaffExists) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "a staff member with this email already exists.",
+			})
+		}
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("staff created", "staff", staff)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "staff created.",
+		"data":    staff,
+	})
+}
+
+var errUnexpectedOrganizer = errors.New("organizer_id is only allowed for the organizer role")
+
+// Organizer accounts must name an existing organizer, other roles must not name one.
+func (ac *AuthController) checkOrganizer(ctx context.Context, role string, organizerID queries.OrganizerID) error {
+	if role != auth.RoleOrganizer {
+		if organizerID != 0 {
+			return errUnexpectedOrganizer
+		}
+		return nil
+	}
+	_, err := ac.Q.Organizer.GetOrganizer(ctx, organizerID)
+	return err
+}
+
+func (ac *AuthController) organizerError(c fiber.Ctx, err error) error {
+	if errors.Is(err, errUnexpectedOrganizer) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": err.Error(),
+		})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "no organizer with the specified ID was found",
+		})
+	}
+	ac.Log.Error(err.Error())
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"code":    http.StatusInternalServerError,
+		"message": "internal server error",
+	})
+}
+
+// CreateAPIKey creates a key for a service account, the key itself is only shown in this response.
+func (ac *AuthController) CreateAPIKey(c fiber.Ctx) error {
+	var req dto.CreateAPIKeyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || !auth.IsStaffRole(req.Role) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "name and a role of admin, organizer or gate_staff are required",
+		})
+	}
+	if err := ac.checkOrganizer(c.Context(), req.Role, req.OrganizerID); err != nil {
+		return ac.organizerError(c, err)
+	}
+	key, err := auth.NewAPIKey()
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	apiKey, err := ac.Q.APIKey.CreateAPIKey(c.Context(), queries.CreateAPIKeyArgs{
+		Name:        req.Name,
+		Role:        req.Role,
+		OrganizerID: req.OrganizerID,
+		KeyHash:     auth.HashAPIKey(key),
+	})
+	if err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("api key created", "api_key", apiKey)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "api key created.",
+		"data": fiber.Map{
+			"api_key": apiKey,
+			"key":     key,
+		},
+	})
+}
+
+func (ac *AuthController) RevokeAPIKey(c fiber.Ctx) error {
+	var req dto.RevokeAPIKeyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	apiKey, err := ac.Q.APIKey.RevokeAPIKey(c.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no active api key with the specified ID was found",
+			})
+		}
+		ac.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	ac.Log.Info("api key revoked", "api_key", apiKey)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "api key revoked.",
+		"data":    apiKey,
+	})
+}
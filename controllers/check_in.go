@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/ticketsign"
+)
+
+var errTicketWrongConcert = errors.New("ticket is for another concert")
+
+// CheckInTicket lets a ticket through the gate exactly once.
+// Two gates scanning the same ticket race on the status transition, only one of them wins.
+func (rc *TicketController) CheckInTicket(c fiber.Ctx) error {
+	var req dto.CheckInTicketRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if _, err := uuid.Parse(req.SerialNumber); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "serial_number is not a valid ticket serial number",
+		})
+	}
+	scannedBy := actor(c)
+	if req.Gate != "" {
+		scannedBy = "gate:" + req.Gate
+	}
+	var ticket queries.Ticket
+	var tcat queries.TicketCategory
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		current, err := q.Ticket.GetTicketBySerialNumber(ctx, req.SerialNumber)
+		if err != nil {
+			return err
+		}
+		if current.ConcertID != req.ConcertID {
+			return errTicketWrongConcert
+		}
+		ticket, err = q.Ticket.TransitionTicket(ctx, current.ID, queries.TicketCheckedIn, scannedBy)
+		if err != nil {
+			return err
+		}
+		tcat, err = q.TicketCategory.GetTicketCategory(ctx, ticket.TicketCategoryID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"code":    http.StatusNotFound,
+				"message": "no ticket with the specified serial number was found",
+			})
+		}
+		if errors.Is(err, errTicketWrongConcert) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket is for another concert.",
+			})
+		}
+		if errors.Is(err, queries.ErrInvalidTicketTransition) {
+			return rc.checkInRejected(c, ticket)
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	rc.Log.Info("ticket checked in", "ticket", ticket, slog.String("actor", scannedBy))
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket checked in.",
+		"data": fiber.Map{
+			"ticket":          ticket,
+			"ticket_category": tcat,
+		},
+	})
+}
+
+// Tells the usher why the ticket cannot get in.
+func (rc *TicketController) checkInRejected(c fiber.Ctx, ticket queries.Ticket) error {
+	switch ticket.Status {
+	case queries.TicketCheckedIn:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"code":          http.StatusConflict,
+			"message":       "ticket was already checked in.",
+			"checked_in_at": ticket.CheckedInAt,
+		})
+	case queries.TicketCancelled, queries.TicketRefunded:
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"code":    http.StatusForbidden,
+			"message": "ticket was cancelled.",
+		})
+	default:
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"code":    http.StatusForbidden,
+			"message": "ticket is not issued yet.",
+		})
+	}
+}
+
+const (
+	scanAccepted  = "accepted"
+	scanDuplicate = "duplicate"
+	scanRejected  = "rejected"
+)
+
+type scanResult struct {
+	Token        string `json:"token"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	ScannedAt    int    `json:"scanned_at"`
+	Result       string `json:"result"`
+	Reason       string `json:"reason,omitempty"`
+	// When the ticket was first let in, set for duplicates.
+	CheckedInAt int `json:"checked_in_at,omitempty"`
+}
+
+// SyncCheckIns takes the scans a gate made while offline, checks the tickets in
+// and reports scans of tickets that were already let in elsewhere.
+func (rc *TicketController) SyncCheckIns(c fiber.Ctx) error {
+	var req dto.SyncCheckInsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	scannedBy := "gate:" + req.Gate + ":offline"
+	verifier := ticketsign.NewVerifier(rc.Signer.PublicKey())
+	// Earliest scan wins when the same ticket shows up twice in the upload.
+	scans := slices.Clone(req.Scans)
+	slices.SortStableFunc(scans, func(a, b dto.OfflineScan) int {
+		return a.ScannedAt - b.ScannedAt
+	})
+	seen := make(map[string]int, len(scans))
+	results := make([]scanResult, 0, len(scans))
+	// Indexes of the results still to be checked in.
+	var pending []int
+	for _, scan := range scans {
+		res := scanResult{Token: scan.Token, ScannedAt: scan.ScannedAt}
+		claims, err := verifier.Verify(scan.Token, time.Unix(int64(scan.ScannedAt), 0))
+		if err != nil {
+			res.Result, res.Reason = scanRejected, err.Error()
+			results = append(results, res)
+			continue
+		}
+		res.SerialNumber = claims.SerialNumber
+		if claims.ConcertID != req.ConcertID {
+			res.Result, res.Reason = scanRejected, errTicketWrongConcert.Error()
+			results = append(results, res)
+			continue
+		}
+		if first, ok := seen[claims.SerialNumber]; ok {
+			res.Result, res.Reason, res.CheckedInAt = scanDuplicate, "scanned twice in this upload", first
+			results = append(results, res)
+			continue
+		}
+		seen[claims.SerialNumber] = scan.ScannedAt
+		pending = append(pending, len(results))
+		results = append(results, res)
+	}
+	// Every valid scan is checked in within a single transaction.
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		for _, i := range pending {
+			res := &results[i]
+			current, err := q.Ticket.GetTicketBySerialNumber(ctx, res.SerialNumber)
+			if errors.Is(err, sql.ErrNoRows) {
+				res.Result, res.Reason = scanRejected, "ticket not found"
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ticket, err := q.Ticket.TransitionTicket(ctx, current.ID, queries.TicketCheckedIn, scannedBy)
+			switch {
+			case err == nil:
+				res.Result = scanAccepted
+			case errors.Is(err, queries.ErrInvalidTicketTransition) && ticket.Status == queries.TicketCheckedIn:
+				res.Result, res.Reason, res.CheckedInAt = scanDuplicate, "already checked in", ticket.CheckedInAt
+			case errors.Is(err, queries.ErrInvalidTicketTransition):
+				res.Result, res.Reason = scanRejected, "ticket is "+ticket.Status
+			default:
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	rc.Log.Info("offline check-ins synced", slog.String("actor", scannedBy), slog.Int("scans", len(results)))
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "offline check-ins synced.",
+		"data":    results,
+	})
+}
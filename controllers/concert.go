@@ -6,19 +6,33 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/go-redsync/redsync/v4"
 	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/config"
 	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
 	"github.com/hendrywilliam/gate-keeper/queries"
 )
 
+// Loads a concert the request may manage.
+// Other organizers' concerts fail with sql.ErrNoRows, as if they did not exist.
+func ownedConcert(c fiber.Ctx, q *queries.Queries, id queries.ConcertID) (queries.Concert, error) {
+	concert, err := q.Concert.GetConcert(c.Context(), id)
+	if err != nil {
+		return concert, err
+	}
+	if scope := middleware.OrganizerScope(c); scope > 0 && concert.OrganizerID != scope {
+		return queries.Concert{}, sql.ErrNoRows
+	}
+	return concert, nil
+}
+
 type ConcertController struct {
-	Mx  *redsync.Mutex
+	Mx  *config.Locker
 	Q   *queries.Queries
 	Log *slog.Logger
 }
 
-func NewConcertController(mx *redsync.Mutex, q *queries.Queries, log *slog.Logger) *ConcertController {
+func NewConcertController(mx *config.Locker, q *queries.Queries, log *slog.Logger) *ConcertController {
 	return &ConcertController{
 		Mx:  mx,
 		Q:   q,
@@ -26,6 +40,7 @@ func NewConcertController(mx *redsync.Mutex, q *queries.Queries, log *slog.Logge
 	}
 }
 
+// Organizers create concerts for themselves, admins may name the organizer.
 func (cc *ConcertController) CreateConcert(c fiber.Ctx) error {
 	var req dto.CreateConcertRequest
 	if err := c.Bind().Body(&req); err != nil {
@@ -35,14 +50,50 @@ func (cc *ConcertController) CreateConcert(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	concert, err := cc.Q.Concert.CreateConcert(c.Context(), queries.CreateConcertQueryArgs{
-		Name:     req.Name,
-		ArtistID: req.ArtistID,
-		VenueID:  req.VenueID,
-		Date:     req.Date,
-		Limit:    req.Limit,
-	})
+	organizerID := middleware.OrganizerScope(c)
+	if organizerID == 0 {
+		organizerID = req.OrganizerID
+	}
+	var concert queries.Concert
+	var err error
+	if organizerID > 0 {
+		_, err = cc.Q.Organizer.GetOrganizer(c.Context(), organizerID)
+	}
+	if err == nil {
+		concert, err = cc.Q.Concert.CreateConcert(c.Context(), queries.CreateConcertQueryArgs{
+			Name:        req.Name,
+			ArtistID:    req.ArtistID,
+			VenueID:     req.VenueID,
+			Date:        req.Date,
+			Limit:       req.Limit,
+			OrganizerID: organizerID,
+		})
+	}
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no organizer with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrArtistNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no artist with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrConcertOverCapacity) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "limit cannot be larger than the venue capacity",
+			})
+		}
 		cc.Log.Error(err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"code":    http.StatusInternalServerError,
@@ -66,7 +117,7 @@ func (cc *ConcertController) DeleteConcert(c fiber.Ctx) error {
 			"message": "failed to process",
 		})
 	}
-	concert, err := cc.Q.Concert.DeleteConcert(c.Context(), req.ID)
+	concert, err := cc.Q.Concert.DeleteConcert(c.Context(), req.ID, middleware.OrganizerScope(c))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -80,7 +131,7 @@ func (cc *ConcertController) DeleteConcert(c fiber.Ctx) error {
 			"message": "internal server error",
 		})
 	}
-	slog.Info("concert updated", "concert", concert)
+	cc.Log.Info("concert deleted", "concert", concert)
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"code":    http.StatusOK,
 		"message": "concert deleted.",
@@ -104,8 +155,34 @@ func (cc *ConcertController) UpdateConcert(c fiber.Ctx) error {
 		VenueID:  req.VenueID,
 		Date:     req.Date,
 		Limit:    req.Limit,
+		// Other organizers' concerts are answered as if they did not exist.
+		OrganizerID: middleware.OrganizerScope(c),
 	})
 	if err != nil {
+		if errors.Is(err, queries.ErrArtistNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no artist with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrConcertOverCapacity) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "limit cannot be larger than the venue capacity",
+			})
+		}
+		if errors.Is(err, queries.ErrConcertSectionsUsed) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket categories are mapped to sections of the current venue.",
+			})
+		}
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"code":    http.StatusBadRequest,
@@ -118,10 +195,143 @@ func (cc *ConcertController) UpdateConcert(c fiber.Ctx) error {
 			"message": "internal server error",
 		})
 	}
-	slog.Error("concert deleted", "concert", concert)
+	cc.Log.Info("concert updated", "concert", concert)
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"code":    http.StatusOK,
 		"message": "concert updated.",
 		"data":    concert,
 	})
 }
+
+// ListConcerts reports the concerts of the signed in organizer, or every concert for admins.
+func (cc *ConcertController) ListConcerts(c fiber.Ctx) error {
+	concerts, err := cc.Q.Concert.ListConcerts(c.Context(), middleware.OrganizerScope(c))
+	if err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "concerts obtained.",
+		"data":    concerts,
+	})
+}
+
+// Page size of SearchConcerts when the client asks for none, and the most it may ask for.
+const (
+	defaultConcertPageSize = 20
+	maxConcertPageSize     = 100
+)
+
+// SearchConcerts is the public concert catalog, filtered and paginated with an opaque cursor.
+func (cc *ConcertController) SearchConcerts(c fiber.Ctx) error {
+	var req dto.SearchConcertsRequest
+	if err := c.Bind().Query(&req); err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultConcertPageSize
+	}
+	if req.Limit < 0 || req.Limit > maxConcertPageSize || req.From < 0 || req.To < 0 || (req.To > 0 && req.To < req.From) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "limit must be between 1 and 100 and from cannot be after to",
+		})
+	}
+	args := queries.SearchConcertsArgs{
+		Query:     req.Query,
+		From:      req.From,
+		To:        req.To,
+		ArtistID:  req.ArtistID,
+		VenueID:   req.VenueID,
+		Available: req.Available,
+		Sort:      req.Sort,
+		Limit:     req.Limit,
+	}
+	var page queries.ConcertPage
+	var err error
+	if req.Cursor != "" {
+		var cursor queries.ConcertCursor
+		cursor, err = queries.DecodeConcertCursor(req.Cursor)
+		args.After = &cursor
+	}
+	if err == nil {
+		page, err = cc.Q.Concert.SearchConcerts(c.Context(), args)
+	}
+	if err != nil {
+		if errors.Is(err, queries.ErrInvalidConcertSort) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "sort must be one of date, -date, name or -name",
+			})
+		}
+		if errors.Is(err, queries.ErrInvalidConcertCursor) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "the cursor is invalid or was made for another sort",
+			})
+		}
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "concerts obtained.",
+		"data":    page,
+	})
+}
+
+// GetConcert reports a concert with its ticket categories and how many tickets are left.
+func (cc *ConcertController) GetConcert(c fiber.Ctx) error {
+	var req dto.GetConcertRequest
+	if err := c.Bind().URI(&req); err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	concert, err := cc.Q.Concert.GetConcert(c.Context(), req.ID)
+	var tcats []queries.TicketCategory
+	if err == nil {
+		tcats, err = cc.Q.TicketCategory.ListTicketCategories(c.Context(), req.ID, 0)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"code":    http.StatusNotFound,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	// The concert limit caps the categories, whichever runs out first.
+	remaining := 0
+	for _, tcat := range tcats {
+		remaining += max(tcat.Remaining, 0)
+	}
+	remaining = min(remaining, concert.Limit)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "concert obtained.",
+		"data": fiber.Map{
+			"concert":           concert,
+			"ticket_categories": tcats,
+			"remaining":         remaining,
+		},
+	})
+}
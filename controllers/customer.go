@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/auth"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+var errNotAuthenticated = errors.New("request does not identify a customer")
+
+// Loads the customer the request acts for.
+// Fails with errNotAuthenticated when there is none and sql.ErrNoRows when the account does not exist.
+func currentCustomer(ctx context.Context, c fiber.Ctx, q queries.Queries) (queries.Customer, error) {
+	id, ok := middleware.CustomerID(c)
+	if !ok {
+		return queries.Customer{}, errNotAuthenticated
+	}
+	customer, err := q.Customer.GetCustomer(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return customer, errNotAuthenticated
+	}
+	return customer, err
+}
+
+// Other customers' tickets are answered as if they did not exist.
+func ownsTicket(c fiber.Ctx, ticket queries.Ticket) bool {
+	id, ok := middleware.CustomerID(c)
+	return ok && ticket.CustomerID == id
+}
+
+func notAuthenticated(c fiber.Ctx) error {
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+		"code":    http.StatusUnauthorized,
+		"message": "sign in as a customer first.",
+	})
+}
+
+type CustomerController struct {
+	Q   *queries.Queries
+	Log *slog.Logger
+}
+
+func NewCustomerController(q *queries.Queries, log *slog.Logger) *CustomerController {
+	return &CustomerController{
+		Q:   q,
+		Log: log,
+	}
+}
+
+func (cc *CustomerController) RegisterCustomer(c fiber.Ctx) error {
+	var req dto.RegisterCustomerRequest
+	if err := c.Bind().Body(&req); err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Email = normalizeEmail(req.Email)
+	if req.Email == "" || len(req.Password) < minPasswordLength {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "email and a password of at least 8 characters are required",
+		})
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	customer, err := cc.Q.Customer.CreateCustomer(c.Context(), queries.CreateCustomerArgs{
+		Email:        req.Email,
+		Name:         strings.TrimSpace(req.Name),
+		PasswordHash: hash,
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrCustomerExists) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "a customer with this email already exists.",
+			})
+		}
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	cc.Log.Info("customer registered", "customer", customer)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "customer registered.",
+		"data":    customer,
+	})
+}
+
+func (cc *CustomerController) GetCurrentCustomer(c fiber.Ctx) error {
+	customer, err := currentCustomer(c.Context(), c, *cc.Q)
+	if err != nil {
+		if errors.Is(err, errNotAuthenticated) {
+			return notAuthenticated(c)
+		}
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "customer obtained.",
+		"data":    customer,
+	})
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/config"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/ticketsign"
+	"github.com/hendrywilliam/gate-keeper/waitroom"
+)
+
+var errCustomerLimitReached = errors.New("customer ticket limit reached")
+
+type OrderController struct {
+	Q      *queries.Queries
+	Log    *slog.Logger
+	Room   *waitroom.WaitRoom
+	Limits config.OrderLimits
+	Signer *ticketsign.Signer
+	// Signed tokens are valid from this long before until this long after the concert date.
+	TokenGrace time.Duration
+}
+
+func NewOrderController(q *queries.Queries, log *slog.Logger, room *waitroom.WaitRoom, limits config.OrderLimits, signer *ticketsign.Signer, tokenGrace time.Duration) *OrderController {
+	return &OrderController{
+		Q:          q,
+		Log:        log,
+		Room:       room,
+		Limits:     limits,
+		Signer:     signer,
+		TokenGrace: tokenGrace,
+	}
+}
+
+// CreateOrder buys every item of the order or nothing at all.
+func (oc *OrderController) CreateOrder(c fiber.Ctx) error {
+	var req dto.CreateOrderRequest
+	if err := c.Bind().Body(&req); err != nil {
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.CustomerEmail = normalizeEmail(req.CustomerEmail)
+	// Customers order for themselves, only the box office orders for another email.
+	if _, ok := middleware.CustomerID(c); ok {
+		customer, err := currentCustomer(c.Context(), c, *oc.Q)
+		if err != nil {
+			if errors.Is(err, errNotAuthenticated) {
+				return notAuthenticated(c)
+			}
+			oc.Log.Error(err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"code":    http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+		}
+		req.CustomerEmail = customer.Email
+	}
+	if req.CustomerEmail == "" || len(req.Items) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "customer_email and at least one item are required",
+		})
+	}
+	quantity := 0
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "item quantity must be positive",
+			})
+		}
+		quantity += item.Quantity
+	}
+	if quantity > oc.Limits.PerOrder {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": fmt.Sprintf("an order can contain at most %d tickets", oc.Limits.PerOrder),
+		})
+	}
+	// The box office of an organizer only sells its own concerts.
+	if _, err := ownedConcert(c, oc.Q, req.ConcertID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	admitted, err := isAdmitted(c, oc.Room, req.ConcertID)
+	if err != nil {
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if !admitted {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"code":    http.StatusForbidden,
+			"message": "join the queue and wait for your turn before buying.",
+		})
+	}
+	var order queries.Order
+	err = queries.ExecTx(c.Context(), oc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		// Tickets are owned by the account of the email, created on the fly for new customers.
+		customer, err := q.Customer.EnsureCustomer(ctx, req.CustomerEmail)
+		if err != nil {
+			return err
+		}
+		if err := q.Order.LockCustomer(ctx, req.ConcertID, customer.ID); err != nil {
+			return err
+		}
+		bought, err := q.Order.CountCustomerTickets(ctx, req.ConcertID, customer.ID)
+		if err != nil {
+			return err
+		}
+		if bought+quantity > oc.Limits.PerCustomer {
+			return errCustomerLimitReached
+		}
+		// All seats are taken at once, a partially filled order is never issued.
+		if _, err := q.Concert.DecrementConcertLimit(ctx, req.ConcertID, quantity); err != nil {
+			return err
+		}
+		// Signed tokens are only valid around the concert date.
+		concert, err := q.Concert.GetConcert(ctx, req.ConcertID)
+		if err != nil {
+			return err
+		}
+		order, err = q.Order.CreateOrder(ctx, queries.CreateOrderArgs{
+			ConcertID:     req.ConcertID,
+			CustomerEmail: req.CustomerEmail,
+			Quantity:      quantity,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range req.Items {
+			if err := checkTicketCategory(ctx, q, req.ConcertID, item.TicketCategoryID); err != nil {
+				return err
+			}
+			// Orders carry no seats, reserved seating is bought one seat at a time.
+			if _, err := checkSeats(ctx, q, req.ConcertID, item.TicketCategoryID, nil); err != nil {
+				return err
+			}
+			if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, item.TicketCategoryID, item.Quantity, 0); err != nil {
+				return err
+			}
+			for range item.Quantity {
+				ticket, err := q.Ticket.CreateTicket(ctx, queries.CreateTicketQueryArgs{
+					ConcertID:        req.ConcertID,
+					TicketCategoryID: item.TicketCategoryID,
+					OrderID:          order.ID,
+					Actor:            actor(c),
+					HolderName:       customer.Name,
+					HolderEmail:      req.CustomerEmail,
+					CustomerID:       customer.ID,
+				})
+				if err != nil {
+					return err
+				}
+				if err := signTicketToken(oc.Signer, oc.TokenGrace, concert, &ticket); err != nil {
+					return err
+				}
+				order.Tickets = append(order.Tickets, ticket)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		oc.Log.Error(err.Error())
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "failed to place the order.",
+			})
+		}
+		if isSeatError(err) {
+			return seatError(c, err)
+		}
+		if errors.Is(err, queries.ErrTicketCategoryConcertMismatch) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket category does not belong to this concert.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleNotStarted) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"code":    http.StatusForbidden,
+				"error":   "not_yet_on_sale",
+				"message": "this ticket category is not on sale yet.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleEnded) {
+			return c.Status(http.StatusGone).JSON(fiber.Map{
+				"code":    http.StatusGone,
+				"error":   "sale_ended",
+				"message": "the sale for this ticket category has ended.",
+			})
+		}
+		if errors.Is(err, errCustomerLimitReached) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("a customer can buy at most %d tickets for this concert", oc.Limits.PerCustomer),
+			})
+		}
+		if errors.Is(err, queries.ErrConcertLimitReached) || errors.Is(err, queries.ErrTicketCategorySoldOut) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "not enough tickets left for this order.",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	oc.Log.Info("order created", "order", order)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "order succeeded.",
+		"data":    order,
+	})
+}
+
+// Emails identify customers, so they are compared case-insensitively.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
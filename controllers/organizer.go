@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+type OrganizerController struct {
+	Q   *queries.Queries
+	Log *slog.Logger
+}
+
+func NewOrganizerController(q *queries.Queries, log *slog.Logger) *OrganizerController {
+	return &OrganizerController{
+		Q:   q,
+		Log: log,
+	}
+}
+
+func (oc *OrganizerController) CreateOrganizer(c fiber.Ctx) error {
+	var req dto.CreateOrganizerRequest
+	if err := c.Bind().Body(&req); err != nil {
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "name is required",
+		})
+	}
+	organizer, err := oc.Q.Organizer.CreateOrganizer(c.Context(), req.Name)
+	if err != nil {
+		if errors.Is(err, queries.ErrOrganizerExists) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "an organizer with this name already exists.",
+			})
+		}
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	oc.Log.Info("organizer created", "organizer", organizer)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "organizer created.",
+		"data":    organizer,
+	})
+}
+
+func (oc *OrganizerController) ListOrganizers(c fiber.Ctx) error {
+	organizers, err := oc.Q.Organizer.ListOrganizers(c.Context())
+	if err != nil {
+		oc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "organizers obtained.",
+		"data":    organizers,
+	})
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/waitroom"
+)
+
+type QueueController struct {
+	Room *waitroom.WaitRoom
+	Q    *queries.Queries
+	Log  *slog.Logger
+	// How often a streamed position is refreshed.
+	StreamInterval time.Duration
+}
+
+func NewQueueController(room *waitroom.WaitRoom, q *queries.Queries, log *slog.Logger, streamInterval time.Duration) *QueueController {
+	return &QueueController{
+		Room:           room,
+		Q:              q,
+		Log:            log,
+		StreamInterval: streamInterval,
+	}
+}
+
+func (qc *QueueController) JoinQueue(c fiber.Ctx) error {
+	var req dto.JoinQueueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	pos, err := qc.Room.Join(c.Context(), req.ConcertID)
+	if err != nil {
+		if errors.Is(err, waitroom.ErrRoomClosed) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no waiting room is open for this concert",
+			})
+		}
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "joined the queue.",
+		"data":    pos,
+	})
+}
+
+func (qc *QueueController) GetQueuePosition(c fiber.Ctx) error {
+	var req dto.QueuePositionRequest
+	if err := c.Bind().Query(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	pos, err := qc.Room.Position(c.Context(), req.ConcertID, req.Token)
+	if err != nil {
+		if errors.Is(err, waitroom.ErrUnknownToken) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"code":    http.StatusNotFound,
+				"message": "queue token not found or admission expired",
+			})
+		}
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "queue position obtained.",
+		"data":    pos,
+	})
+}
+
+// Streams the queue position as server-sent events until the user is admitted.
+func (qc *QueueController) StreamQueuePosition(c fiber.Ctx) error {
+	var req dto.QueuePositionRequest
+	if err := c.Bind().Query(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		// The request context is gone once the handler returns.
+		ctx := context.Background()
+		for {
+			pos, err := qc.Room.Position(ctx, req.ConcertID, req.Token)
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+				w.Flush()
+				return
+			}
+			j, _ := json.Marshal(pos)
+			fmt.Fprintf(w, "event: position\ndata: %s\n\n", j)
+			// Client went away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+			if pos.Admitted {
+				return
+			}
+			time.Sleep(qc.StreamInterval)
+		}
+	})
+}
+
+func (qc *QueueController) OpenQueue(c fiber.Ctx) error {
+	var req dto.OpenQueueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.BatchSize <= 0 || req.AdmissionTTL <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "batch_size and admission_ttl must be positive",
+		})
+	}
+	_, err := ownedConcert(c, qc.Q, req.ConcertID)
+	var room waitroom.Room
+	if err == nil {
+		room, err = qc.Room.Open(c.Context(), req.ConcertID, req.BatchSize, time.Duration(req.AdmissionTTL)*time.Second)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	qc.Log.Info("waiting room opened", slog.Int("concert_id", req.ConcertID))
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "waiting room opened.",
+		"data":    room,
+	})
+}
+
+func (qc *QueueController) PauseQueue(c fiber.Ctx) error {
+	return qc.toggleQueue(c, qc.Room.Pause, "waiting room paused.")
+}
+
+func (qc *QueueController) ResumeQueue(c fiber.Ctx) error {
+	return qc.toggleQueue(c, qc.Room.Resume, "waiting room resumed.")
+}
+
+func (qc *QueueController) toggleQueue(c fiber.Ctx, fn func(context.Context, int) (waitroom.Room, error), message string) error {
+	var req dto.QueueAdminRequest
+	if err := c.Bind().Body(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	_, err := ownedConcert(c, qc.Q, req.ConcertID)
+	var room waitroom.Room
+	if err == nil {
+		room, err = fn(c.Context(), req.ConcertID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		if errors.Is(err, waitroom.ErrRoomClosed) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no waiting room is open for this concert",
+			})
+		}
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	qc.Log.Info(message, slog.Int("concert_id", req.ConcertID))
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": message,
+		"data":    room,
+	})
+}
+
+func (qc *QueueController) DrainQueue(c fiber.Ctx) error {
+	var req dto.QueueAdminRequest
+	if err := c.Bind().Body(&req); err != nil {
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	_, err := ownedConcert(c, qc.Q, req.ConcertID)
+	var dropped int
+	if err == nil {
+		dropped, err = qc.Room.Drain(c.Context(), req.ConcertID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		qc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	qc.Log.Info("waiting room drained", slog.Int("concert_id", req.ConcertID), slog.Int("dropped", dropped))
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "waiting room drained.",
+		"data": fiber.Map{
+			"dropped": dropped,
+		},
+	})
+}
+
+// Hot concerts only accept buyers admitted by the waiting room.
+// The admission is used up by the check, a buyer whose purchase fails has to queue again.
+func isAdmitted(c fiber.Ctx, room *waitroom.WaitRoom, concertID int) (bool, error) {
+	guarded, err := room.IsGuarded(c.Context(), concertID)
+	if err != nil {
+		return false, err
+	}
+	if !guarded {
+		return true, nil
+	}
+	return room.Consume(c.Context(), concertID, c.Get("X-Queue-Token"))
+}
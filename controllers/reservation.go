@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+// ReserveTicket holds tickets for ReservationTTL, they have to be confirmed before it runs out.
+func (rc *TicketController) ReserveTicket(c fiber.Ctx) error {
+	var req dto.ReserveTicketRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.Quantity == 0 {
+		req.Quantity = len(req.SeatIDs)
+	}
+	if req.Quantity <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "quantity must be positive",
+		})
+	}
+	if req.Quantity > rc.Limits.PerOrder {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": fmt.Sprintf("a reservation can contain at most %d tickets", rc.Limits.PerOrder),
+		})
+	}
+	if len(req.SeatIDs) > 0 && len(req.SeatIDs) != req.Quantity {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "seat_ids must list one seat per ticket",
+		})
+	}
+	customerID, ok := middleware.CustomerID(c)
+	if !ok {
+		return notAuthenticated(c)
+	}
+	admitted, err := isAdmitted(c, rc.Room, req.ConcertID)
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if !admitted {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"code":    http.StatusForbidden,
+			"message": "join the queue and wait for your turn before buying.",
+		})
+	}
+	if len(req.SeatIDs) > 0 {
+		release, err := rc.holdSeats(c.Context(), req.ConcertID, req.SeatIDs)
+		if err != nil {
+			return seatBusy(c)
+		}
+		defer release()
+	}
+	var reservation queries.Reservation
+	err = queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		// Reserved tickets count towards the per-customer maximum like bought ones.
+		if err := q.Order.LockCustomer(ctx, req.ConcertID, customerID); err != nil {
+			return err
+		}
+		reserved, err := q.Order.CountCustomerTickets(ctx, req.ConcertID, customerID)
+		if err != nil {
+			return err
+		}
+		if reserved+req.Quantity > rc.Limits.PerCustomer {
+			return errCustomerLimitReached
+		}
+		if err := checkTicketCategory(ctx, q, req.ConcertID, req.TicketCategoryID); err != nil {
+			return err
+		}
+		if _, err := checkSeats(ctx, q, req.ConcertID, req.TicketCategoryID, req.SeatIDs); err != nil {
+			return err
+		}
+		if _, err := q.Concert.DecrementConcertLimit(ctx, req.ConcertID, req.Quantity); err != nil {
+			return err
+		}
+		if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, req.TicketCategoryID, 0, req.Quantity); err != nil {
+			return err
+		}
+		reservation, err = q.Reservation.CreateReservation(ctx, queries.CreateReservationArgs{
+			ConcertID:        req.ConcertID,
+			TicketCategoryID: req.TicketCategoryID,
+			CustomerID:       customerID,
+			Quantity:         req.Quantity,
+			ExpiresAt:        int(time.Now().Add(rc.ReservationTTL).Unix()),
+		})
+		if err != nil {
+			return err
+		}
+		// Serial numbers are handed out right away, the tickets stay reserved until confirmed.
+		// Reserved tickets hold their seats until the reservation is confirmed, released or expired.
+		for i := range req.Quantity {
+			var seatID queries.SeatID
+			if len(req.SeatIDs) > 0 {
+				seatID = req.SeatIDs[i]
+			}
+			ticket, err := q.Ticket.CreateTicket(ctx, queries.CreateTicketQueryArgs{
+				ConcertID:        req.ConcertID,
+				TicketCategoryID: req.TicketCategoryID,
+				ReservationID:    reservation.ID,
+				Status:           queries.TicketReserved,
+				Actor:            actor(c),
+				HolderName:       strings.TrimSpace(req.HolderName),
+				HolderEmail:      normalizeEmail(req.HolderEmail),
+				CustomerID:       customerID,
+				SeatID:           seatID,
+			})
+			if err != nil {
+				return err
+			}
+			reservation.Tickets = append(reservation.Tickets, ticket)
+		}
+		return nil
+	})
+	if err != nil {
+		rc.Log.Error(err.Error())
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "failed to reserve tickets.",
+			})
+		}
+		if isSeatError(err) {
+			return seatError(c, err)
+		}
+		if errors.Is(err, errCustomerLimitReached) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("a customer can buy at most %d tickets for this concert", rc.Limits.PerCustomer),
+			})
+		}
+		if errors.Is(err, queries.ErrTicketCategoryConcertMismatch) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket category does not belong to this concert.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleNotStarted) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"code":    http.StatusForbidden,
+				"error":   "not_yet_on_sale",
+				"message": "this ticket category is not on sale yet.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleEnded) {
+			return c.Status(http.StatusGone).JSON(fiber.Map{
+				"code":    http.StatusGone,
+				"error":   "sale_ended",
+				"message": "the sale for this ticket category has ended.",
+			})
+		}
+		if errors.Is(err, queries.ErrConcertLimitReached) {
+			return c.Status(http.StatusOK).JSON(fiber.Map{
+				"code":    http.StatusOK,
+				"message": "failed to reserve tickets. limit reached :(",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketCategorySoldOut) {
+			return c.Status(http.StatusOK).JSON(fiber.Map{
+				"code":    http.StatusOK,
+				"message": "failed to reserve tickets. ticket category sold out :(",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	rc.Log.Info("reservation created", "reservation", reservation)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "tickets reserved.",
+		"data":    reservation,
+	})
+}
+
+// ConfirmReservation issues the reserved tickets.
+func (rc *TicketController) ConfirmReservation(c fiber.Ctx) error {
+	var req dto.ConfirmReservationRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	customerID, ok := middleware.CustomerID(c)
+	if !ok {
+		return notAuthenticated(c)
+	}
+	var tickets []queries.Ticket
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		reservation, err := q.Reservation.CloseReservation(ctx, req.ID, customerID, queries.ReservationConfirmed)
+		if err != nil {
+			return err
+		}
+		// Held seats become sold seats.
+		if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, reservation.TicketCategoryID, reservation.Quantity, -reservation.Quantity); err != nil {
+			return err
+		}
+		tickets, err = q.Ticket.TransitionReservationTickets(ctx, reservation.ID, queries.TicketIssued, actor(c))
+		if err != nil {
+			return err
+		}
+		concert, err := q.Concert.GetConcert(ctx, reservation.ConcertID)
+		if err != nil {
+			return err
+		}
+		for i := range tickets {
+			if err := rc.signTicketFor(concert, &tickets[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rc.reservationError(c, err)
+	}
+	rc.Log.Info("reservation confirmed", slog.Int("reservation_id", req.ID), slog.Int("tickets", len(tickets)))
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "booking succeeded.",
+		"data":    tickets,
+	})
+}
+
+// ReleaseReservation gives the held tickets back before the reservation expires.
+func (rc *TicketController) ReleaseReservation(c fiber.Ctx) error {
+	var req dto.ReleaseReservationRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	customerID, ok := middleware.CustomerID(c)
+	if !ok {
+		return notAuthenticated(c)
+	}
+	var reservation queries.Reservation
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		var err error
+		reservation, err = q.Reservation.CloseReservation(ctx, req.ID, customerID, queries.ReservationReleased)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Ticket.TransitionReservationTickets(ctx, reservation.ID, queries.TicketCancelled, actor(c)); err != nil {
+			return err
+		}
+		if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, reservation.TicketCategoryID, 0, -reservation.Quantity); err != nil {
+			return err
+		}
+		_, err = q.Concert.IncrementConcertLimit(ctx, reservation.ConcertID, reservation.Quantity)
+		return err
+	})
+	if err != nil {
+		return rc.reservationError(c, err)
+	}
+	rc.Log.Info("reservation released", "reservation", reservation)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "reservation released.",
+		"data":    reservation,
+	})
+}
+
+func (rc *TicketController) reservationError(c fiber.Ctx, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "no reservation with the specified ID was found",
+		})
+	}
+	if errors.Is(err, queries.ErrReservationExpired) {
+		return c.Status(http.StatusGone).JSON(fiber.Map{
+			"code":    http.StatusGone,
+			"message": "reservation expired.",
+		})
+	}
+	if errors.Is(err, queries.ErrReservationNotHeld) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"code":    http.StatusConflict,
+			"message": "reservation was already confirmed or released.",
+		})
+	}
+	rc.Log.Error(err.Error())
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"code":    http.StatusInternalServerError,
+		"message": "internal server error",
+	})
+}
+
+// SweepReservations expires reservations past their TTL every interval
+// and returns their tickets to the concert and category, until ctx is cancelled.
+func (rc *TicketController) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var expired []queries.Reservation
+			err := queries.ExecTx(ctx, rc.Q.DB, func(q queries.Queries) error {
+				var err error
+				expired, err = q.Reservation.ExpireReservations(ctx)
+				if err != nil {
+					return err
+				}
+				for _, r := range expired {
+					if _, err := q.Ticket.TransitionReservationTickets(ctx, r.ID, queries.TicketCancelled, systemActor); err != nil {
+						return err
+					}
+					if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, r.TicketCategoryID, 0, -r.Quantity); err != nil {
+						return err
+					}
+					if _, err := q.Concert.IncrementConcertLimit(ctx, r.ConcertID, r.Quantity); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				rc.Log.Error(err.Error())
+				continue
+			}
+			if len(expired) > 0 {
+				rc.Log.Info("reservations expired", slog.Int("count", len(expired)))
+			}
+		}
+	}
+}
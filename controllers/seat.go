@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/allocator"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+// Locks the seats for the rest of the purchase, in ID order so buyers of overlapping seats cannot deadlock.
+// The returned func releases every lock.
+func (rc *TicketController) holdSeats(ctx context.Context, concertID queries.ConcertID, seatIDs []queries.SeatID) (func(), error) {
+	ids := slices.Clone(seatIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	held := make([]*redsync.Mutex, 0, len(ids))
+	release := func() {
+		for _, mx := range held {
+			mx.Unlock()
+		}
+	}
+	for _, id := range ids {
+		mx := rc.Mx.SeatMutex(concertID, id)
+		if err := rc.Mx.Acquire(ctx, mx); err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, mx)
+	}
+	return release, nil
+}
+
+var errSeatBusy = errors.New("seat is held by another buyer")
+
+// How many times a buyer without chosen seats gets new ones picked when others took them first.
+const allocateAttempts = 3
+
+// Picks and holds the best seats of a reserved seating category for a buyer who did not choose any,
+// general admission categories get no seats. The returned func releases the holds.
+func (rc *TicketController) allocateSeats(ctx context.Context, concertID queries.ConcertID, categoryID queries.TicketCategoryID, req allocator.Request) ([]queries.SeatID, func(), error) {
+	tcat, err := rc.Q.TicketCategory.GetTicketCategory(ctx, categoryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A category of another concert is reported by the purchase itself.
+	if len(tcat.SectionIDs) == 0 || tcat.ConcertID != concertID {
+		return nil, func() {}, nil
+	}
+	for attempt := 1; ; attempt++ {
+		concertSeats, err := rc.Q.Seat.ListConcertSeats(ctx, concertID, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		seats := make([]allocator.Seat, 0, len(concertSeats))
+		for _, s := range concertSeats {
+			if !slices.Contains(tcat.SectionIDs, s.SectionID) {
+				continue
+			}
+			seats = append(seats, allocator.Seat{
+				ID:              s.ID,
+				SectionID:       s.SectionID,
+				SectionPriority: s.SectionPriority,
+				Row:             s.Row,
+				RowRank:         s.RowRank,
+				Number:          s.Number,
+				Available:       s.Status == queries.SeatAvailable,
+			})
+		}
+		if len(seats) == 0 {
+			return nil, func() {}, nil
+		}
+		picked, err := allocator.Allocate(seats, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		seatIDs := make([]queries.SeatID, 0, len(picked))
+		for _, s := range picked {
+			seatIDs = append(seatIDs, s.ID)
+		}
+		release, err := rc.holdSeats(ctx, concertID, seatIDs)
+		if err != nil {
+			return nil, nil, errSeatBusy
+		}
+		// Somebody may have bought the seats between the listing and the hold.
+		_, err = checkSeats(ctx, *rc.Q, concertID, categoryID, seatIDs)
+		if err == nil {
+			return seatIDs, release, nil
+		}
+		release()
+		if !errors.Is(err, queries.ErrSeatTaken) || attempt == allocateAttempts {
+			return nil, nil, err
+		}
+	}
+}
+
+// Makes sure the seats fit the category: reserved seating categories need one free seat
+// of their sections per ticket, general admission categories take none.
+func checkSeats(ctx context.Context, q queries.Queries, concertID queries.ConcertID, categoryID queries.TicketCategoryID, seatIDs []queries.SeatID) ([]queries.Seat, error) {
+	tcat, err := q.TicketCategory.GetTicketCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	seated := false
+	if len(tcat.SectionIDs) > 0 {
+		count, err := q.Seat.CountSeats(ctx, tcat.SectionIDs)
+		if err != nil {
+			return nil, err
+		}
+		seated = count > 0
+	}
+	if len(seatIDs) == 0 {
+		if seated {
+			return nil, queries.ErrSeatRequired
+		}
+		return nil, nil
+	}
+	if !seated {
+		return nil, queries.ErrSeatGeneralAdmission
+	}
+	seats := make([]queries.Seat, 0, len(seatIDs))
+	for i, id := range seatIDs {
+		if slices.Contains(seatIDs[:i], id) {
+			return nil, queries.ErrSeatTaken
+		}
+		seat, err := q.Seat.CheckSeat(ctx, concertID, tcat.SectionIDs, id)
+		if err != nil {
+			return nil, err
+		}
+		seats = append(seats, seat)
+	}
+	return seats, nil
+}
+
+func isSeatError(err error) bool {
+	return errors.Is(err, queries.ErrSeatTaken) ||
+		errors.Is(err, queries.ErrSeatNotInSection) ||
+		errors.Is(err, queries.ErrSeatRequired) ||
+		errors.Is(err, queries.ErrSeatGeneralAdmission)
+}
+
+func seatError(c fiber.Ctx, err error) error {
+	if errors.Is(err, queries.ErrSeatTaken) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"code":    http.StatusConflict,
+			"message": "the seat is already taken.",
+		})
+	}
+	if errors.Is(err, queries.ErrSeatNotInSection) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "the seat does not belong to this ticket category.",
+		})
+	}
+	if errors.Is(err, queries.ErrSeatRequired) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "this ticket category is reserved seating, pick one seat per ticket.",
+		})
+	}
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+		"code":    http.StatusBadRequest,
+		"message": "this ticket category is general admission and has no seats.",
+	})
+}
+
+func allocationError(c fiber.Ctx, err error) error {
+	if errors.Is(err, allocator.ErrNotEnoughSeats) {
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"code":    http.StatusOK,
+			"message": "failed to buy a ticket. not enough seats left :(",
+		})
+	}
+	return c.Status(http.StatusConflict).JSON(fiber.Map{
+		"code":    http.StatusConflict,
+		"error":   "no_adjacent_seats",
+		"message": "no row has room for the whole group. set allow_split to accept seats apart.",
+	})
+}
+
+func seatBusy(c fiber.Ctx) error {
+	return c.Status(http.StatusConflict).JSON(fiber.Map{
+		"code":    http.StatusConflict,
+		"message": "the seat is being bought by someone else. try again later.",
+	})
+}
+
+// ListConcertSeats reports every seat of the concert venue and whether it is available, held or sold.
+func (cc *ConcertController) ListConcertSeats(c fiber.Ctx) error {
+	var req dto.ListConcertSeatsRequest
+	if err := c.Bind().Query(&req); err != nil {
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	_, err := cc.Q.Concert.GetConcert(c.Context(), req.ConcertID)
+	var seats []queries.ConcertSeat
+	if err == nil {
+		seats, err = cc.Q.Seat.ListConcertSeats(c.Context(), req.ConcertID, req.SectionID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		cc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "concert seats obtained.",
+		"data":    seats,
+	})
+}
+
+// CreateSeats adds a row of numbered seats to a venue section.
+func (vc *VenueController) CreateSeats(c fiber.Ctx) error {
+	var req dto.CreateSeatsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Row = strings.TrimSpace(req.Row)
+	if req.Row == "" || req.From <= 0 || req.To < req.From {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "row and a positive range of seat numbers are required",
+		})
+	}
+	if req.RowRank < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "row_rank cannot be negative",
+		})
+	}
+	var seats []queries.Seat
+	err := queries.ExecTx(c.Context(), vc.Q.DB, func(q queries.Queries) error {
+		var err error
+		seats, err = q.Seat.CreateSeats(c.Context(), queries.CreateSeatsArgs{
+			SectionID: req.SectionID,
+			Row:       req.Row,
+			RowRank:   req.RowRank,
+			From:      req.From,
+			To:        req.To,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue section with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrSeatsExceedSection) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "seats cannot exceed the section capacity",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("seats created", "section_id", req.SectionID, "row", req.Row, "count", len(seats))
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "seats created.",
+		"data":    seats,
+	})
+}
+
+func (vc *VenueController) ListSeats(c fiber.Ctx) error {
+	var req dto.ListSeatsRequest
+	if err := c.Bind().Query(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	seats, err := vc.Q.Seat.ListSeats(c.Context(), req.SectionID)
+	if err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "seats obtained.",
+		"data":    seats,
+	})
+}
+
+func (vc *VenueController) DeleteSeat(c fiber.Ctx) error {
+	var req dto.DeleteSeatRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	seat, err := vc.Q.Seat.DeleteSeat(c.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no seat with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrSeatInUse) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "tickets were already issued for the seat.",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("seat deleted", "seat", seat)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "seat deleted.",
+		"data":    seat,
+	})
+}
@@ -1,46 +1,70 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/go-redsync/redsync/v4"
 	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/allocator"
+	"github.com/hendrywilliam/gate-keeper/config"
 	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
 	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/ticketsign"
+	"github.com/hendrywilliam/gate-keeper/waitroom"
 )
 
+// Actor recorded for changes not caused by a request.
+const systemActor = "system"
+
+var errTicketReserved = errors.New("ticket is reserved")
+
 type TicketController struct {
-	Mx  *redsync.Mutex
-	Q   *queries.Queries
-	Log *slog.Logger
+	Mx       *config.Locker
+	Q        *queries.Queries
+	Log      *slog.Logger
+	Strategy config.PurchaseStrategy
+	Room     *waitroom.WaitRoom
+	// How long a reservation holds its tickets.
+	ReservationTTL time.Duration
+	Refund         config.RefundPolicy
+	Signer         *ticketsign.Signer
+	// Signed tokens are valid from this long before until this long after the concert date.
+	TokenGrace time.Duration
+	// How long a recipient has to accept a ticket transfer.
+	TransferTTL time.Duration
+	Limits      config.OrderLimits
+	// Dates printed on tickets are shown in this location.
+	Location *time.Location
 }
 
-func NewTicketController(mx *redsync.Mutex, q *queries.Queries, log *slog.Logger) *TicketController {
+func NewTicketController(mx *config.Locker, q *queries.Queries, log *slog.Logger, strategy config.PurchaseStrategy, room *waitroom.WaitRoom, reservationTTL time.Duration, refund config.RefundPolicy, signer *ticketsign.Signer, tokenGrace time.Duration, transferTTL time.Duration, limits config.OrderLimits, location *time.Location) *TicketController {
 	return &TicketController{
-		Mx:  mx,
-		Q:   q,
-		Log: log,
+		Mx:             mx,
+		Q:              q,
+		Log:            log,
+		Strategy:       strategy,
+		Room:           room,
+		ReservationTTL: reservationTTL,
+		Refund:         refund,
+		Signer:         signer,
+		TokenGrace:     tokenGrace,
+		TransferTTL:    transferTTL,
+		Limits:         limits,
+		Location:       location,
 	}
 }
 
 func (rc *TicketController) BuyTicket(c fiber.Ctx) error {
-	if err := rc.Mx.Lock(); err != nil {
-		// Lock is not granted.
-		// either an internal error occured or there is an ongoing process hehe :D
-		return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{
-			"code":    http.StatusTooManyRequests,
-			"message": "too many request. try again later.",
-		})
-	}
-	rc.Log.Info("lock granted", slog.String("ip request", c.IP()))
-	// Release granted lock.
-	defer func() {
-		rc.Mx.Unlock()
-		rc.Log.Info("lock released", slog.String("ip request", c.IP()))
-	}()
 	var req dto.BuyTicketRequest
 	if err := c.Bind().Body(&req); err != nil {
 		rc.Log.Error(err.Error())
@@ -49,31 +73,164 @@ func (rc *TicketController) BuyTicket(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	var ticket queries.Ticket
-	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
-		ctx := c.Context()
-		concert, err := q.Concert.GetConcert(ctx, req.ConcertID)
-		if err != nil {
-			return err
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "quantity must be positive",
+		})
+	}
+	if req.Quantity > rc.Limits.PerOrder {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": fmt.Sprintf("a purchase can contain at most %d tickets", rc.Limits.PerOrder),
+		})
+	}
+	if req.SeatID > 0 && req.Quantity > 1 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "a chosen seat buys a single ticket, leave seat_id out to get seats allocated",
+		})
+	}
+	customer, err := currentCustomer(c.Context(), c, *rc.Q)
+	if err != nil {
+		if errors.Is(err, errNotAuthenticated) {
+			return notAuthenticated(c)
 		}
-		if concert.Limit == 0 {
-			return errors.New("concert limit reached")
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	// The buyer holds the ticket unless it is bought for somebody else.
+	holderName, holderEmail := strings.TrimSpace(req.HolderName), normalizeEmail(req.HolderEmail)
+	if holderEmail == "" {
+		holderName, holderEmail = customer.Name, customer.Email
+	}
+	admitted, err := isAdmitted(c, rc.Room, req.ConcertID)
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if !admitted {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"code":    http.StatusForbidden,
+			"message": "join the queue and wait for your turn before buying.",
+		})
+	}
+	var seatIDs []queries.SeatID
+	if req.SeatID > 0 {
+		seatIDs = []queries.SeatID{req.SeatID}
+		// Held whatever the strategy, the seat is never shared between buyers.
+		release, err := rc.holdSeats(c.Context(), req.ConcertID, seatIDs)
+		if err != nil {
+			return seatBusy(c)
 		}
-		ticket, err = q.Ticket.CreateTicket(ctx, queries.CreateTicketQueryArgs{
-			ConcertID:        req.ConcertID,
-			TicketCategoryID: req.TicketCategoryID,
+		defer release()
+	} else {
+		var release func()
+		seatIDs, release, err = rc.allocateSeats(c.Context(), req.ConcertID, req.TicketCategoryID, allocator.Request{
+			Quantity:   req.Quantity,
+			AllowSplit: req.AllowSplit,
 		})
 		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"code":    http.StatusBadRequest,
+					"message": "failed to buy a ticket.",
+				})
+			}
+			if errors.Is(err, allocator.ErrNotEnoughSeats) || errors.Is(err, allocator.ErrNoAdjacentSeats) {
+				return allocationError(c, err)
+			}
+			if errors.Is(err, errSeatBusy) || errors.Is(err, queries.ErrSeatTaken) {
+				return seatBusy(c)
+			}
+			rc.Log.Error(err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"code":    http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+		}
+		defer release()
+	}
+	if rc.Strategy == config.PurchaseStrategyRedsync {
+		// Only purchases for the same concert compete for the lock.
+		mx := rc.Mx.ConcertMutex(req.ConcertID)
+		if err := rc.Mx.Acquire(c.Context(), mx); err != nil {
+			// Lock is not granted within the wait budget.
+			// either an internal error occured or there is an ongoing process hehe :D
+			retryAfter := int(math.Ceil(rc.Mx.Policy.RetryAfter.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{
+				"code":        http.StatusTooManyRequests,
+				"message":     "too many request. try again later.",
+				"retry_after": retryAfter,
+			})
+		}
+		rc.Log.Info("lock granted", slog.String("ip request", c.IP()), slog.Int("concert_id", req.ConcertID))
+		// Release granted lock.
+		defer func() {
+			mx.Unlock()
+			rc.Log.Info("lock released", slog.String("ip request", c.IP()), slog.Int("concert_id", req.ConcertID))
+		}()
+	}
+	tickets := make([]queries.Ticket, 0, req.Quantity)
+	err = queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		// Same per-customer maximum as orders, serialized with them.
+		if err := q.Order.LockCustomer(ctx, req.ConcertID, customer.ID); err != nil {
 			return err
 		}
-		concert.Limit--
-		_, err = q.Concert.UpdateConcert(ctx, queries.UpdateConcertArgs{
-			ID:    req.ConcertID,
-			Limit: concert.Limit,
-		})
+		bought, err := q.Order.CountCustomerTickets(ctx, req.ConcertID, customer.ID)
 		if err != nil {
 			return err
 		}
+		if bought+req.Quantity > rc.Limits.PerCustomer {
+			return errCustomerLimitReached
+		}
+		if err := checkTicketCategory(ctx, q, req.ConcertID, req.TicketCategoryID); err != nil {
+			return err
+		}
+		if _, err := checkSeats(ctx, q, req.ConcertID, req.TicketCategoryID, seatIDs); err != nil {
+			return err
+		}
+		// Conditional decrement in both strategies, reservations, orders and cancellations
+		// move the limit without taking the concert lock.
+		if _, err := q.Concert.DecrementConcertLimit(ctx, req.ConcertID, req.Quantity); err != nil {
+			return err
+		}
+		if _, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, req.TicketCategoryID, req.Quantity, 0); err != nil {
+			return err
+		}
+		for i := range req.Quantity {
+			var seatID queries.SeatID
+			if len(seatIDs) > 0 {
+				seatID = seatIDs[i]
+			}
+			ticket, err := q.Ticket.CreateTicket(ctx, queries.CreateTicketQueryArgs{
+				ConcertID:        req.ConcertID,
+				TicketCategoryID: req.TicketCategoryID,
+				Actor:            actor(c),
+				HolderName:       holderName,
+				HolderEmail:      holderEmail,
+				CustomerID:       customer.ID,
+				SeatID:           seatID,
+			})
+			if err != nil {
+				return err
+			}
+			if err := rc.signTicket(ctx, q, &ticket); err != nil {
+				return err
+			}
+			tickets = append(tickets, ticket)
+		}
 		return nil
 	})
 	if err != nil {
@@ -84,25 +241,144 @@ func (rc *TicketController) BuyTicket(c fiber.Ctx) error {
 				"message": "failed to buy a ticket.",
 			})
 		}
-		if errors.Is(err, errors.New("concert limit reached")) {
+		if isSeatError(err) {
+			return seatError(c, err)
+		}
+		if errors.Is(err, errCustomerLimitReached) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("a customer can buy at most %d tickets for this concert", rc.Limits.PerCustomer),
+			})
+		}
+		if errors.Is(err, queries.ErrTicketCategoryConcertMismatch) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket category does not belong to this concert.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleNotStarted) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"code":    http.StatusForbidden,
+				"error":   "not_yet_on_sale",
+				"message": "this ticket category is not on sale yet.",
+			})
+		}
+		if errors.Is(err, queries.ErrSaleEnded) {
+			return c.Status(http.StatusGone).JSON(fiber.Map{
+				"code":    http.StatusGone,
+				"error":   "sale_ended",
+				"message": "the sale for this ticket category has ended.",
+			})
+		}
+		if errors.Is(err, queries.ErrConcertLimitReached) {
 			return c.Status(http.StatusOK).JSON(fiber.Map{
 				"code":    http.StatusOK,
 				"message": "failed to buy a ticket. limit reached :(",
 			})
 		}
+		if errors.Is(err, queries.ErrTicketCategorySoldOut) {
+			return c.Status(http.StatusOK).JSON(fiber.Map{
+				"code":    http.StatusOK,
+				"message": "failed to buy a ticket. ticket category sold out :(",
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"code":    http.StatusInternalServerError,
 			"message": "internal server error",
 		})
 	}
-	rc.Log.Info("ticket created", "ticket", ticket)
+	for _, ticket := range tickets {
+		rc.Log.Info("ticket created", "ticket", ticket)
+	}
+	// A single ticket keeps the response it always had.
+	var data any = tickets
+	if len(tickets) == 1 {
+		data = tickets[0]
+	}
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
 		"code":    http.StatusCreated,
 		"message": "booking succeeded.",
-		"data":    ticket,
+		"data":    data,
 	})
 }
 
+// Who is behind the request, recorded in the ticket history.
+func actor(c fiber.Ctx) string {
+	if principal, ok := middleware.CurrentPrincipal(c); ok {
+		return principal.String()
+	}
+	return "ip:" + c.IP()
+}
+
+// Makes sure the category belongs to the concert being bought and is on sale right now.
+func checkTicketCategory(ctx context.Context, q queries.Queries, concertID queries.ConcertID, id queries.TicketCategoryID) error {
+	tcat, err := q.TicketCategory.GetTicketCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+	if tcat.ConcertID != concertID {
+		return queries.ErrTicketCategoryConcertMismatch
+	}
+	return tcat.CheckSaleWindow(time.Now())
+}
+
+// Attaches a token the gate can verify offline, only to tickets that can still get in.
+func (rc *TicketController) signTicket(ctx context.Context, q queries.Queries, ticket *queries.Ticket) error {
+	if ticket.Status != queries.TicketIssued && ticket.Status != queries.TicketTransferred {
+		return nil
+	}
+	concert, err := q.Concert.GetConcert(ctx, ticket.ConcertID)
+	if err != nil {
+		return err
+	}
+	return rc.signTicketFor(concert, ticket)
+}
+
+// Signs a batch of tickets, looking each concert up only once.
+func (rc *TicketController) signTickets(ctx context.Context, tickets []queries.Ticket) error {
+	concerts := map[queries.ConcertID]queries.Concert{}
+	for i := range tickets {
+		concert, ok := concerts[tickets[i].ConcertID]
+		if !ok {
+			var err error
+			if concert, err = rc.Q.Concert.GetConcert(ctx, tickets[i].ConcertID); err != nil {
+				return err
+			}
+			concerts[tickets[i].ConcertID] = concert
+		}
+		if err := rc.signTicketFor(concert, &tickets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Same as signTicket, for callers that already hold the concert.
+func (rc *TicketController) signTicketFor(concert queries.Concert, ticket *queries.Ticket) error {
+	return signTicketToken(rc.Signer, rc.TokenGrace, concert, ticket)
+}
+
+// Tokens are valid from grace before until grace after the concert date,
+// tokens of concerts without a date do not expire.
+func signTicketToken(signer *ticketsign.Signer, grace time.Duration, concert queries.Concert, ticket *queries.Ticket) error {
+	if ticket.Status != queries.TicketIssued && ticket.Status != queries.TicketTransferred {
+		return nil
+	}
+	claims := ticketsign.Claims{
+		SerialNumber:     ticket.SerialNumber,
+		ConcertID:        ticket.ConcertID,
+		TicketCategoryID: ticket.TicketCategoryID,
+	}
+	if concert.Date > 0 {
+		date := time.Unix(int64(concert.Date), 0)
+		claims.NotBefore = date.Add(-grace).Unix()
+		claims.ExpiresAt = date.Add(grace).Unix()
+	}
+	var err error
+	ticket.Token, err = signer.Sign(claims)
+	return err
+}
+
 func (rc *TicketController) GetTicket(c fiber.Ctx) error {
 	var req dto.GetTicketRequest
 	if err := c.Bind().Body(&req); err != nil {
@@ -112,7 +388,16 @@ func (rc *TicketController) GetTicket(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
 	ticket, err := rc.Q.Ticket.GetTicket(c.Context(), req.ID)
+	if err == nil && !ownsTicket(c, ticket) {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		err = rc.signTicket(c.Context(), *rc.Q, &ticket)
+	}
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -134,6 +419,7 @@ func (rc *TicketController) GetTicket(c fiber.Ctx) error {
 	})
 }
 
+// CancelTicket gives the seat back and records the refund owed under the refund policy.
 func (rc *TicketController) CancelTicket(c fiber.Ctx) error {
 	var req dto.GetTicketRequest
 	if err := c.Bind().Body(&req); err != nil {
@@ -143,7 +429,57 @@ func (rc *TicketController) CancelTicket(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	ticket, err := rc.Q.Ticket.DeleteTicket(c.Context(), req.ID)
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
+	var ticket queries.Ticket
+	var refund queries.Refund
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		current, err := q.Ticket.GetTicket(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		if !ownsTicket(c, current) {
+			return sql.ErrNoRows
+		}
+		// Reserved tickets are only held, they go back through the reservation.
+		if current.Status == queries.TicketReserved {
+			return errTicketReserved
+		}
+		ticket, err = q.Ticket.TransitionTicket(ctx, req.ID, queries.TicketCancelled, actor(c))
+		if err != nil {
+			return err
+		}
+		tcat, err := q.TicketCategory.AdjustTicketCategoryInventory(ctx, ticket.TicketCategoryID, -1, 0)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Concert.IncrementConcertLimit(ctx, ticket.ConcertID, 1); err != nil {
+			return err
+		}
+		concert, err := q.Concert.GetConcert(ctx, ticket.ConcertID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var concertDate time.Time
+		if concert.Date > 0 {
+			concertDate = time.Unix(int64(concert.Date), 0)
+		}
+		refund, err = q.Refund.CreateRefund(ctx, queries.CreateRefundArgs{
+			TicketID:   ticket.ID,
+			Amount:     rc.Refund.Amount(tcat.Price, concertDate, now),
+			Percentage: rc.Refund.PercentageAt(concertDate, now),
+		})
+		if err != nil {
+			return err
+		}
+		if refund.Amount > 0 {
+			ticket, err = q.Ticket.TransitionTicket(ctx, ticket.ID, queries.TicketRefunded, actor(c))
+		}
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -151,16 +487,108 @@ func (rc *TicketController) CancelTicket(c fiber.Ctx) error {
 				"message": "no ticket with the specified ID was found",
 			})
 		}
+		if errors.Is(err, errTicketReserved) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket is only reserved. release the reservation instead.",
+			})
+		}
+		if errors.Is(err, queries.ErrInvalidTicketTransition) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket cannot be cancelled in its current state.",
+			})
+		}
 		rc.Log.Error(err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"code":    http.StatusInternalServerError,
 			"message": "internal server error",
 		})
 	}
-	rc.Log.Error("ticket deleted", "ticket", ticket)
+	rc.Log.Info("ticket cancelled", "ticket", ticket, "refund", refund)
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"code":    http.StatusOK,
 		"message": "ticket canceled.",
-		"data":    ticket,
+		"data": fiber.Map{
+			"ticket": ticket,
+			"refund": refund,
+		},
+	})
+}
+
+func (rc *TicketController) GetTicketHistory(c fiber.Ctx) error {
+	var req dto.GetTicketRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
+	ticket, err := rc.Q.Ticket.GetTicket(c.Context(), req.ID)
+	if err == nil && !ownsTicket(c, ticket) {
+		err = sql.ErrNoRows
+	}
+	var history []queries.TicketStatusChange
+	if err == nil {
+		history, err = rc.Q.Ticket.ListTicketHistory(c.Context(), req.ID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket with the specified ID was found",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket history obtained.",
+		"data":    history,
+	})
+}
+
+// GetTicketPublicKey serves the key gate scanners use to verify ticket tokens offline.
+func (rc *TicketController) GetTicketPublicKey(c fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket public key obtained.",
+		"data": fiber.Map{
+			"algorithm":  "ed25519",
+			"public_key": base64.StdEncoding.EncodeToString(rc.Signer.PublicKey()),
+		},
+	})
+}
+
+// ListCustomerTickets lists the tickets owned by the customer, newest first.
+func (rc *TicketController) ListCustomerTickets(c fiber.Ctx) error {
+	customerID, ok := middleware.CustomerID(c)
+	if !ok {
+		return notAuthenticated(c)
+	}
+	ctx := c.Context()
+	tickets, err := rc.Q.Ticket.ListCustomerTickets(ctx, customerID)
+	if err == nil {
+		err = rc.signTickets(ctx, tickets)
+	}
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "tickets obtained.",
+		"data":    tickets,
 	})
 }
@@ -5,20 +5,24 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
-	"github.com/go-redsync/redsync/v4"
 	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/config"
 	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
 	"github.com/hendrywilliam/gate-keeper/queries"
 )
 
+var errConcertNotFound = errors.New("concert not found")
+
 type TicketCategoryController struct {
-	Mx  *redsync.Mutex
+	Mx  *config.Locker
 	Q   *queries.Queries
 	Log *slog.Logger
 }
 
-func NewTicketCategoryController(mx *redsync.Mutex, q *queries.Queries, log *slog.Logger) *TicketCategoryController {
+func NewTicketCategoryController(mx *config.Locker, q *queries.Queries, log *slog.Logger) *TicketCategoryController {
 	return &TicketCategoryController{
 		Mx:  mx,
 		Q:   q,
@@ -35,14 +39,48 @@ func (tc *TicketCategoryController) CreateTicketCategory(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	tcat, err := tc.Q.TicketCategory.CreateTicketCategory(c.Context(), queries.CreateTicketCategoryArgs{
-		ConcertID:   req.ConcertID,
-		Description: req.Description,
-		Price:       req.Price,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
+	// A category without capacity could never be sold.
+	if req.Capacity <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "capacity must be positive",
+		})
+	}
+	var tcat queries.TicketCategory
+	// The sections stay locked until commit, so concurrent categories cannot overrun them.
+	err := queries.ExecTx(c.Context(), tc.Q.DB, func(q queries.Queries) error {
+		var err error
+		tcat, err = q.TicketCategory.CreateTicketCategory(c.Context(), queries.CreateTicketCategoryArgs{
+			ConcertID:   req.ConcertID,
+			Description: req.Description,
+			Price:       req.Price,
+			StartDate:   req.StartDate,
+			EndDate:     req.EndDate,
+			Capacity:    req.Capacity,
+			SectionIDs:  req.SectionIDs,
+			OrganizerID: middleware.OrganizerScope(c),
+		})
+		return err
 	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionVenueMismatch) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "every section must belong to the concert venue",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionCapacityReached) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket categories of the concert cannot exceed the capacity of their sections",
+			})
+		}
 		tc.Log.Error(err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"code":    http.StatusInternalServerError,
@@ -66,13 +104,34 @@ func (tc *TicketCategoryController) UpdateTicketCategory(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	tcat, err := tc.Q.TicketCategory.UpdateTicketCategory(c.Context(), queries.UpdateTicketCategoryArgs{
-		ID:          req.ID,
-		ConcertID:   req.ConcertID,
-		Description: req.Description,
-		Price:       req.Price,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
+	if req.Capacity <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "capacity must be positive",
+		})
+	}
+	var tcat queries.TicketCategory
+	err := queries.ExecTx(c.Context(), tc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		if _, err := q.Concert.GetConcert(ctx, req.ConcertID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errConcertNotFound
+			}
+			return err
+		}
+		var err error
+		tcat, err = q.TicketCategory.UpdateTicketCategory(ctx, queries.UpdateTicketCategoryArgs{
+			ID:          req.ID,
+			ConcertID:   req.ConcertID,
+			Description: req.Description,
+			Price:       req.Price,
+			StartDate:   req.StartDate,
+			EndDate:     req.EndDate,
+			Capacity:    req.Capacity,
+			SectionIDs:  req.SectionIDs,
+			OrganizerID: middleware.OrganizerScope(c),
+		})
+		return err
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -81,6 +140,36 @@ func (tc *TicketCategoryController) UpdateTicketCategory(c fiber.Ctx) error {
 				"message": "no ticket category with the specified ID was found",
 			})
 		}
+		if errors.Is(err, queries.ErrVenueSectionVenueMismatch) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "every section must belong to the concert venue",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionCapacityReached) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "ticket categories of the concert cannot exceed the capacity of their sections",
+			})
+		}
+		if errors.Is(err, errConcertNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketCategoryInUse) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket category already has tickets for its concert and cannot be moved",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketCategoryCapacityTooLow) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "capacity cannot be lower than sold and held tickets",
+			})
+		}
 		tc.Log.Error(err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"code":    http.StatusInternalServerError,
@@ -104,7 +193,7 @@ func (tc *TicketCategoryController) DeleteTicketCategory(c fiber.Ctx) error {
 			"message": "failed to process data",
 		})
 	}
-	tcat, err := tc.Q.TicketCategory.DeleteTicketCategory(c.Context(), req.ID)
+	tcat, err := tc.Q.TicketCategory.DeleteTicketCategory(c.Context(), req.ID, middleware.OrganizerScope(c))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -125,3 +214,59 @@ func (tc *TicketCategoryController) DeleteTicketCategory(c fiber.Ctx) error {
 		"data":    tcat,
 	})
 }
+
+// ListTicketCategories reports every category of a concert with its remaining seats.
+func (tc *TicketCategoryController) ListTicketCategories(c fiber.Ctx) error {
+	var req dto.ListTicketCategoriesRequest
+	if err := c.Bind().Query(&req); err != nil {
+		tc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	tcats, err := tc.Q.TicketCategory.ListTicketCategories(c.Context(), req.ConcertID, middleware.OrganizerScope(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		tc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket categories obtained.",
+		"data":    tcats,
+	})
+}
+
+// ListPurchasableTicketCategories reports the categories of a concert that can be bought right now.
+func (tc *TicketCategoryController) ListPurchasableTicketCategories(c fiber.Ctx) error {
+	var req dto.ListTicketCategoriesRequest
+	if err := c.Bind().Query(&req); err != nil {
+		tc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	tcats, err := tc.Q.TicketCategory.ListPurchasableTicketCategories(c.Context(), req.ConcertID, time.Now())
+	if err != nil {
+		tc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "purchasable ticket categories obtained.",
+		"data":    tcats,
+	})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/ticketcode"
+)
+
+const (
+	defaultCodeSize = 300
+	maxCodeSize     = 2000
+)
+
+// GetTicketCode renders the ticket's signed token or serial number as an image,
+// so it can be embedded in emails and wallet passes as is.
+func (rc *TicketController) GetTicketCode(c fiber.Ctx) error {
+	var req dto.GetTicketCodeRequest
+	if err := c.Bind().Query(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.Symbology == "" {
+		req.Symbology = ticketcode.SymbologyQR
+	}
+	if req.Format == "" {
+		req.Format = ticketcode.FormatPNG
+	}
+	if req.Content == "" {
+		req.Content = "token"
+	}
+	if req.Size == 0 {
+		req.Size = defaultCodeSize
+	}
+	if req.Size < 0 || req.Size > maxCodeSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "size must be between 1 and 2000 pixels",
+		})
+	}
+	if req.Content != "token" && req.Content != "serial_number" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "content must be token or serial_number",
+		})
+	}
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
+	ticket, err := rc.Q.Ticket.GetTicket(c.Context(), req.ID)
+	if err == nil && !ownsTicket(c, ticket) {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		err = rc.signTicket(c.Context(), *rc.Q, &ticket)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket with the specified ID was found",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	content := ticket.SerialNumber
+	if req.Content == "token" {
+		// Only tickets that can still get in are signed.
+		if ticket.Token == "" {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket is " + ticket.Status + " and has no token.",
+			})
+		}
+		content = ticket.Token
+	}
+	img, err := ticketcode.Render(content, req.Symbology, req.Format, req.Size)
+	if err != nil {
+		if errors.Is(err, ticketcode.ErrUnknownSymbology) || errors.Is(err, ticketcode.ErrUnknownFormat) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": err.Error(),
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	c.Set(fiber.HeaderContentType, ticketcode.ContentType(req.Format))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Status(http.StatusOK).Send(img)
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/ticketpdf"
+)
+
+// Tickets worth printing in a bulk download, the rest cannot get in.
+var printableStatuses = []queries.TicketStatus{queries.TicketIssued, queries.TicketTransferred}
+
+// GetTicketPDF renders a single printable ticket.
+func (rc *TicketController) GetTicketPDF(c fiber.Ctx) error {
+	var req dto.GetTicketPDFRequest
+	if err := c.Bind().Query(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
+	ctx := c.Context()
+	ticket, err := rc.Q.Ticket.GetTicket(ctx, req.ID)
+	if err == nil && !ownsTicket(c, ticket) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket with the specified ID was found",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	entries, err := rc.ticketEntries(ctx, []queries.Ticket{ticket})
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return rc.sendPDF(c, fmt.Sprintf("ticket-%s.pdf", ticket.SerialNumber), entries)
+}
+
+// GetConcertTicketsPDF renders every ticket of a concert that can still get in,
+// for box offices printing tickets in bulk.
+func (rc *TicketController) GetConcertTicketsPDF(c fiber.Ctx) error {
+	var req dto.GetConcertTicketsPDFRequest
+	if err := c.Bind().Query(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	ctx := c.Context()
+	if _, err := ownedConcert(c, rc.Q, req.ConcertID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no concert with the specified ID was found",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	tickets, err := rc.Q.Ticket.ListConcertTickets(ctx, req.ConcertID, printableStatuses)
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if len(tickets) == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"code":    http.StatusNotFound,
+			"message": "the concert has no printable tickets.",
+		})
+	}
+	entries, err := rc.ticketEntries(ctx, tickets)
+	if err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return rc.sendPDF(c, fmt.Sprintf("concert-%d-tickets.pdf", req.ConcertID), entries)
+}
+
+// Gathers concert and category details for each ticket, looking each one up only once.
+func (rc *TicketController) ticketEntries(ctx context.Context, tickets []queries.Ticket) ([]ticketpdf.Entry, error) {
+	concerts := map[queries.ConcertID]queries.Concert{}
+	categories := map[queries.TicketCategoryID]queries.TicketCategory{}
+	entries := make([]ticketpdf.Entry, 0, len(tickets))
+	for _, ticket := range tickets {
+		concert, ok := concerts[ticket.ConcertID]
+		if !ok {
+			var err error
+			if concert, err = rc.Q.Concert.GetConcert(ctx, ticket.ConcertID); err != nil {
+				return nil, err
+			}
+			concert.ID = ticket.ConcertID
+			concerts[ticket.ConcertID] = concert
+		}
+		category, ok := categories[ticket.TicketCategoryID]
+		if !ok {
+			var err error
+			if category, err = rc.Q.TicketCategory.GetTicketCategory(ctx, ticket.TicketCategoryID); err != nil {
+				return nil, err
+			}
+			categories[ticket.TicketCategoryID] = category
+		}
+		if err := rc.signTicketFor(concert, &ticket); err != nil {
+			return nil, err
+		}
+		entries = append(entries, ticketpdf.Entry{
+			Concert:  concert,
+			Category: category,
+			Ticket:   ticket,
+		})
+	}
+	return entries, nil
+}
+
+func (rc *TicketController) sendPDF(c fiber.Ctx, filename string, entries []ticketpdf.Entry) error {
+	var buf bytes.Buffer
+	if err := ticketpdf.Render(&buf, entries, rc.Location); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Status(http.StatusOK).Send(buf.Bytes())
+}
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/middleware"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+var (
+	errTransferToHolder = errors.New("ticket cannot be transferred to its holder")
+	errWrongRecipient   = errors.New("transfer is addressed to another customer")
+)
+
+func hashTransferCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// InitiateTicketTransfer starts handing a ticket over to another customer.
+// The acceptance code is returned once, for the holder to pass on to the recipient.
+func (rc *TicketController) InitiateTicketTransfer(c fiber.Ctx) error {
+	var req dto.InitiateTicketTransferRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.RecipientEmail = normalizeEmail(req.RecipientEmail)
+	if req.RecipientEmail == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "recipient_email is required",
+		})
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	code := hex.EncodeToString(b)
+	var transfer queries.TicketTransfer
+	var ticket queries.Ticket
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		customer, err := currentCustomer(ctx, c, q)
+		if err != nil {
+			return err
+		}
+		ticket, err = q.Ticket.GetTicket(ctx, req.TicketID)
+		if err != nil {
+			return err
+		}
+		if !ownsTicket(c, ticket) {
+			return sql.ErrNoRows
+		}
+		if customer.Email == req.RecipientEmail {
+			return errTransferToHolder
+		}
+		if !queries.CanTransitionTicket(ticket.Status, queries.TicketTransferred) {
+			return queries.ErrInvalidTicketTransition
+		}
+		transfer, err = q.TicketTransfer.CreateTicketTransfer(ctx, queries.CreateTicketTransferArgs{
+			TicketID:         ticket.ID,
+			FromName:         ticket.HolderName,
+			FromEmail:        customer.Email,
+			ToEmail:          req.RecipientEmail,
+			FromSerialNumber: ticket.SerialNumber,
+			CodeHash:         hashTransferCode(code),
+			ExpiresAt:        int(time.Now().Add(rc.TransferTTL).Unix()),
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errNotAuthenticated) {
+			return notAuthenticated(c)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket with the specified ID was found",
+			})
+		}
+		if errors.Is(err, errTransferToHolder) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "the recipient already holds this ticket.",
+			})
+		}
+		if errors.Is(err, queries.ErrInvalidTicketTransition) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket is " + ticket.Status + " and cannot be transferred.",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketTransferPending) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket already has a pending transfer.",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	rc.Log.Info("ticket transfer initiated", "transfer", transfer)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "ticket transfer initiated.",
+		"data": fiber.Map{
+			"transfer": transfer,
+			"code":     code,
+		},
+	})
+}
+
+// AcceptTicketTransfer hands the ticket to the recipient under a new serial number,
+// so the codes the previous holder has no longer get in.
+func (rc *TicketController) AcceptTicketTransfer(c fiber.Ctx) error {
+	var req dto.AcceptTicketTransferRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	var transfer queries.TicketTransfer
+	var ticket queries.Ticket
+	err := queries.ExecTx(c.Context(), rc.Q.DB, func(q queries.Queries) error {
+		ctx := c.Context()
+		customer, err := currentCustomer(ctx, c, q)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSpace(req.RecipientName)
+		if name == "" {
+			name = customer.Name
+		}
+		transfer, err = q.TicketTransfer.AcceptTicketTransfer(ctx, queries.AcceptTicketTransferArgs{
+			ID:             req.TransferID,
+			CodeHash:       hashTransferCode(req.Code),
+			ToName:         name,
+			ToSerialNumber: uuid.NewString(),
+		})
+		if err != nil {
+			return err
+		}
+		if transfer.ToEmail != customer.Email {
+			return errWrongRecipient
+		}
+		ticket, err = q.Ticket.TransitionTicket(ctx, transfer.TicketID, queries.TicketTransferred, "customer:"+transfer.ToEmail)
+		if err != nil {
+			return err
+		}
+		ticket, err = q.Ticket.ReassignTicket(ctx, ticket.ID, transfer.ToSerialNumber, transfer.ToName, transfer.ToEmail, customer.ID)
+		if err != nil {
+			return err
+		}
+		return rc.signTicket(ctx, q, &ticket)
+	})
+	if err != nil {
+		if errors.Is(err, errNotAuthenticated) {
+			return notAuthenticated(c)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket transfer with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketTransferCode) || errors.Is(err, errWrongRecipient) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"code":    http.StatusForbidden,
+				"message": "the transfer code or recipient does not match.",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketTransferExpired) {
+			return c.Status(http.StatusGone).JSON(fiber.Map{
+				"code":    http.StatusGone,
+				"message": "the ticket transfer has expired.",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketTransferNotPending) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket transfer is " + transfer.Status + ".",
+			})
+		}
+		if errors.Is(err, queries.ErrInvalidTicketTransition) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket is " + ticket.Status + " and cannot be transferred.",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	rc.Log.Info("ticket transferred", "transfer", transfer, "ticket", ticket)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket transferred.",
+		"data": fiber.Map{
+			"transfer": transfer,
+			"ticket":   ticket,
+		},
+	})
+}
+
+func (rc *TicketController) CancelTicketTransfer(c fiber.Ctx) error {
+	var req dto.CancelTicketTransferRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	customer, err := currentCustomer(c.Context(), c, *rc.Q)
+	var transfer queries.TicketTransfer
+	if err == nil {
+		transfer, err = rc.Q.TicketTransfer.CancelTicketTransfer(c.Context(), req.TransferID, customer.Email)
+	}
+	if err != nil {
+		if errors.Is(err, errNotAuthenticated) {
+			return notAuthenticated(c)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket transfer with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrTicketTransferNotPending) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket transfer is " + transfer.Status + ".",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket transfer cancelled.",
+		"data":    transfer,
+	})
+}
+
+func (rc *TicketController) ListTicketTransfers(c fiber.Ctx) error {
+	var req dto.GetTicketRequest
+	if err := c.Bind().Body(&req); err != nil {
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if _, ok := middleware.CustomerID(c); !ok {
+		return notAuthenticated(c)
+	}
+	ticket, err := rc.Q.Ticket.GetTicket(c.Context(), req.ID)
+	if err == nil && !ownsTicket(c, ticket) {
+		err = sql.ErrNoRows
+	}
+	var transfers []queries.TicketTransfer
+	if err == nil {
+		transfers, err = rc.Q.TicketTransfer.ListTicketTransfers(c.Context(), req.ID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no ticket with the specified ID was found",
+			})
+		}
+		rc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "ticket transfers obtained.",
+		"data":    transfers,
+	})
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+type VenueController struct {
+	Q   *queries.Queries
+	Log *slog.Logger
+}
+
+func NewVenueController(q *queries.Queries, log *slog.Logger) *VenueController {
+	return &VenueController{
+		Q:   q,
+		Log: log,
+	}
+}
+
+func (vc *VenueController) CreateVenue(c fiber.Ctx) error {
+	var req dto.CreateVenueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "name is required",
+		})
+	}
+	if req.Capacity < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "capacity cannot be negative",
+		})
+	}
+	venue, err := vc.Q.Venue.CreateVenue(c.Context(), queries.CreateVenueArgs{
+		Name:     req.Name,
+		Address:  strings.TrimSpace(req.Address),
+		City:     strings.TrimSpace(req.City),
+		Capacity: req.Capacity,
+	})
+	if err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue created", "venue", venue)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "venue created.",
+		"data":    venue,
+	})
+}
+
+func (vc *VenueController) ListVenues(c fiber.Ctx) error {
+	venues, err := vc.Q.Venue.ListVenues(c.Context())
+	if err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venues obtained.",
+		"data":    venues,
+	})
+}
+
+func (vc *VenueController) UpdateVenue(c fiber.Ctx) error {
+	var req dto.UpdateVenueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.Capacity < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "capacity cannot be negative",
+		})
+	}
+	venue, err := vc.Q.Venue.UpdateVenue(c.Context(), queries.UpdateVenueArgs{
+		ID:       req.ID,
+		Name:     strings.TrimSpace(req.Name),
+		Address:  strings.TrimSpace(req.Address),
+		City:     strings.TrimSpace(req.City),
+		Capacity: req.Capacity,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueCapacityTooLow) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "capacity cannot be lower than the venue sections or the limit of its concerts",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue updated", "venue", venue)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venue updated.",
+		"data":    venue,
+	})
+}
+
+func (vc *VenueController) DeleteVenue(c fiber.Ctx) error {
+	var req dto.DeleteVenueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	venue, err := vc.Q.Venue.DeleteVenue(c.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueInUse) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "venue still hosts concerts.",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue deleted", "venue", venue)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venue deleted.",
+		"data":    venue,
+	})
+}
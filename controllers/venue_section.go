@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/dto"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+func (vc *VenueController) CreateVenueSection(c fiber.Ctx) error {
+	var req dto.CreateVenueSectionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Capacity <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "name and a positive capacity are required",
+		})
+	}
+	if req.Priority < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "priority cannot be negative",
+		})
+	}
+	var section queries.VenueSection
+	// The venue stays locked until commit, so concurrent sections cannot overrun it.
+	err := queries.ExecTx(c.Context(), vc.Q.DB, func(q queries.Queries) error {
+		var err error
+		section, err = q.VenueSection.CreateVenueSection(c.Context(), queries.CreateVenueSectionArgs{
+			VenueID:  req.VenueID,
+			Name:     req.Name,
+			Capacity: req.Capacity,
+			Priority: req.Priority,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionExists) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "the venue already has a section with this name.",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueCapacityExceeded) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "sections cannot exceed the venue capacity",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue section created", "venue_section", section)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"code":    http.StatusCreated,
+		"message": "venue section created.",
+		"data":    section,
+	})
+}
+
+func (vc *VenueController) ListVenueSections(c fiber.Ctx) error {
+	var req dto.ListVenueSectionsRequest
+	if err := c.Bind().Query(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	sections, err := vc.Q.VenueSection.ListVenueSections(c.Context(), req.VenueID)
+	if err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venue sections obtained.",
+		"data":    sections,
+	})
+}
+
+func (vc *VenueController) UpdateVenueSection(c fiber.Ctx) error {
+	var req dto.UpdateVenueSectionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	if req.Capacity < 0 || req.Priority < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"code":    http.StatusBadRequest,
+			"message": "capacity and priority cannot be negative",
+		})
+	}
+	var section queries.VenueSection
+	err := queries.ExecTx(c.Context(), vc.Q.DB, func(q queries.Queries) error {
+		var err error
+		section, err = q.VenueSection.UpdateVenueSection(c.Context(), queries.UpdateVenueSectionArgs{
+			ID:       req.ID,
+			Name:     strings.TrimSpace(req.Name),
+			Capacity: req.Capacity,
+			Priority: req.Priority,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue section with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueCapacityExceeded) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "sections cannot exceed the venue capacity",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionCapacityTooLow) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "capacity cannot be lower than the seats or ticket categories of the section",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue section updated", "venue_section", section)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venue section updated.",
+		"data":    section,
+	})
+}
+
+func (vc *VenueController) DeleteVenueSection(c fiber.Ctx) error {
+	var req dto.DeleteVenueSectionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"code":    http.StatusUnprocessableEntity,
+			"message": "failed to process data",
+		})
+	}
+	section, err := vc.Q.VenueSection.DeleteVenueSection(c.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"code":    http.StatusBadRequest,
+				"message": "no venue section with the specified ID was found",
+			})
+		}
+		if errors.Is(err, queries.ErrVenueSectionInUse) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "ticket categories are still mapped to the section.",
+			})
+		}
+		vc.Log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	vc.Log.Info("venue section deleted", "venue_section", section)
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"code":    http.StatusOK,
+		"message": "venue section deleted.",
+		"data":    section,
+	})
+}
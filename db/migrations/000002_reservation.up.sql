@@ -0,0 +1,16 @@
+CREATE TABLE "reservation" (
+    "id" serial PRIMARY KEY,
+    "concert_id" integer,
+    "ticket_category_id" integer,
+    "quantity" int,
+    "status" varchar(16) DEFAULT 'held',
+    "expires_at" int,
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "reservation" ADD FOREIGN KEY ("concert_id") REFERENCES "concert" ("id");
+
+ALTER TABLE "reservation" ADD FOREIGN KEY ("ticket_category_id") REFERENCES "ticket_category" ("id");
+
+CREATE INDEX ON "reservation" ("status", "expires_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "ticket" DROP COLUMN IF EXISTS "order_id";
+
+DROP TABLE IF EXISTS orders;
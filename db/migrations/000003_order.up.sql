@@ -0,0 +1,16 @@
+CREATE TABLE "orders" (
+    "id" serial PRIMARY KEY,
+    "concert_id" integer,
+    "customer_email" varchar(255),
+    "quantity" int,
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "orders" ADD FOREIGN KEY ("concert_id") REFERENCES "concert" ("id");
+
+CREATE INDEX ON "orders" ("concert_id", "customer_email");
+
+ALTER TABLE "ticket" ADD COLUMN "order_id" integer;
+
+ALTER TABLE "ticket" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id");
@@ -0,0 +1,5 @@
+ALTER TABLE "ticket_category"
+    DROP CONSTRAINT IF EXISTS "ticket_category_inventory_check",
+    DROP COLUMN IF EXISTS "capacity",
+    DROP COLUMN IF EXISTS "sold",
+    DROP COLUMN IF EXISTS "held";
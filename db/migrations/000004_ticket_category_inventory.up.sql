@@ -0,0 +1,13 @@
+ALTER TABLE "ticket_category"
+    ADD COLUMN "capacity" int NOT NULL DEFAULT 0,
+    ADD COLUMN "sold" int NOT NULL DEFAULT 0,
+    ADD COLUMN "held" int NOT NULL DEFAULT 0;
+
+-- Existing categories keep selling from what is left of their concert.
+UPDATE "ticket_category" tc
+SET "sold" = (SELECT COUNT(*) FROM "ticket" t WHERE t.ticket_category_id = tc.id),
+    "capacity" = (SELECT COUNT(*) FROM "ticket" t WHERE t.ticket_category_id = tc.id)
+        + COALESCE((SELECT c."limit" FROM "concert" c WHERE c.id = tc.concert_id), 0);
+
+ALTER TABLE "ticket_category" ADD CONSTRAINT "ticket_category_inventory_check"
+    CHECK ("sold" >= 0 AND "held" >= 0 AND "sold" + "held" <= "capacity");
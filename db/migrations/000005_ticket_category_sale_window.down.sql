@@ -0,0 +1,5 @@
+ALTER TABLE "ticket_category"
+    ALTER COLUMN "start_date" DROP NOT NULL,
+    ALTER COLUMN "start_date" DROP DEFAULT,
+    ALTER COLUMN "end_date" DROP NOT NULL,
+    ALTER COLUMN "end_date" DROP DEFAULT;
@@ -0,0 +1,10 @@
+UPDATE "ticket_category" SET "start_date" = 0 WHERE "start_date" IS NULL;
+
+UPDATE "ticket_category" SET "end_date" = 0 WHERE "end_date" IS NULL;
+
+-- Zero leaves that side of the sale window open.
+ALTER TABLE "ticket_category"
+    ALTER COLUMN "start_date" SET DEFAULT 0,
+    ALTER COLUMN "start_date" SET NOT NULL,
+    ALTER COLUMN "end_date" SET DEFAULT 0,
+    ALTER COLUMN "end_date" SET NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS refund;
+
+ALTER TABLE "ticket"
+    DROP COLUMN IF EXISTS "status",
+    DROP COLUMN IF EXISTS "cancelled_at";
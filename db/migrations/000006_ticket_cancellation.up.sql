@@ -0,0 +1,14 @@
+ALTER TABLE "ticket"
+    ADD COLUMN "status" varchar(16) NOT NULL DEFAULT 'issued',
+    ADD COLUMN "cancelled_at" int;
+
+CREATE TABLE "refund" (
+    "id" serial PRIMARY KEY,
+    "ticket_id" integer,
+    "amount" decimal(10,2),
+    "percentage" decimal(5,2),
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "refund" ADD FOREIGN KEY ("ticket_id") REFERENCES "ticket" ("id");
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ticket_status_history;
+
+ALTER TABLE "ticket"
+    DROP CONSTRAINT IF EXISTS "ticket_status_check",
+    DROP COLUMN IF EXISTS "reservation_id";
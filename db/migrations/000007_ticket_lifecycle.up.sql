@@ -0,0 +1,19 @@
+ALTER TABLE "ticket" ADD COLUMN "reservation_id" integer;
+
+ALTER TABLE "ticket" ADD FOREIGN KEY ("reservation_id") REFERENCES "reservation" ("id");
+
+ALTER TABLE "ticket" ADD CONSTRAINT "ticket_status_check"
+    CHECK ("status" IN ('reserved', 'issued', 'checked_in', 'cancelled', 'refunded', 'transferred'));
+
+CREATE TABLE "ticket_status_history" (
+    "id" serial PRIMARY KEY,
+    "ticket_id" integer,
+    "from_status" varchar(16),
+    "to_status" varchar(16),
+    "actor" varchar(255),
+    "created_at" int
+);
+
+ALTER TABLE "ticket_status_history" ADD FOREIGN KEY ("ticket_id") REFERENCES "ticket" ("id");
+
+CREATE INDEX ON "ticket_status_history" ("ticket_id");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS ticket_serial_number_idx;
+
+ALTER TABLE "ticket" DROP COLUMN IF EXISTS "checked_in_at";
@@ -0,0 +1,3 @@
+ALTER TABLE "ticket" ADD COLUMN "checked_in_at" int;
+
+CREATE UNIQUE INDEX ON "ticket" ("serial_number");
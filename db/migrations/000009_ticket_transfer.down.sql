@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ticket_transfer;
+
+ALTER TABLE "ticket"
+    DROP COLUMN IF EXISTS "holder_name",
+    DROP COLUMN IF EXISTS "holder_email";
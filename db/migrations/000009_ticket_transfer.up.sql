@@ -0,0 +1,31 @@
+ALTER TABLE "ticket"
+    ADD COLUMN "holder_name" varchar(255),
+    ADD COLUMN "holder_email" varchar(255);
+
+UPDATE "ticket" SET "holder_email" = "orders"."customer_email"
+FROM "orders"
+WHERE "ticket"."order_id" = "orders"."id";
+
+CREATE TABLE "ticket_transfer" (
+    "id" serial PRIMARY KEY,
+    "ticket_id" integer,
+    "status" varchar(16) NOT NULL DEFAULT 'pending'
+        CHECK ("status" IN ('pending', 'accepted', 'cancelled', 'expired')),
+    "from_name" varchar(255),
+    "from_email" varchar(255),
+    "to_name" varchar(255),
+    "to_email" varchar(255),
+    "code_hash" varchar(64),
+    "from_serial_number" uuid,
+    "to_serial_number" uuid,
+    "expires_at" int,
+    "accepted_at" int,
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "ticket_transfer" ADD FOREIGN KEY ("ticket_id") REFERENCES "ticket" ("id");
+
+CREATE INDEX ON "ticket_transfer" ("ticket_id");
+
+CREATE UNIQUE INDEX "ticket_transfer_pending_idx" ON "ticket_transfer" ("ticket_id") WHERE "status" = 'pending';
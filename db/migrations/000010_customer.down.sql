@@ -0,0 +1,3 @@
+ALTER TABLE "ticket" DROP COLUMN IF EXISTS "customer_id";
+
+DROP TABLE IF EXISTS customer;
@@ -0,0 +1,25 @@
+CREATE TABLE "customer" (
+    "id" serial PRIMARY KEY,
+    "email" varchar(255) NOT NULL UNIQUE,
+    "name" varchar(255),
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "ticket" ADD COLUMN "customer_id" integer;
+
+ALTER TABLE "ticket" ADD FOREIGN KEY ("customer_id") REFERENCES "customer" ("id");
+
+CREATE INDEX ON "ticket" ("customer_id");
+
+INSERT INTO "customer" ("email", "name", "created_at", "updated_at")
+SELECT DISTINCT ON ("holder_email") "holder_email", "holder_name",
+    extract(epoch FROM now())::int, extract(epoch FROM now())::int
+FROM "ticket"
+WHERE "holder_email" IS NOT NULL
+ORDER BY "holder_email", "id" DESC
+ON CONFLICT ("email") DO NOTHING;
+
+UPDATE "ticket" SET "customer_id" = "customer"."id"
+FROM "customer"
+WHERE "ticket"."holder_email" = "customer"."email";
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS api_key;
+
+DROP TABLE IF EXISTS staff;
+
+ALTER TABLE "customer" DROP COLUMN IF EXISTS "password_hash";
@@ -0,0 +1,21 @@
+ALTER TABLE "customer" ADD COLUMN "password_hash" varchar(255);
+
+CREATE TABLE "staff" (
+    "id" serial PRIMARY KEY,
+    "email" varchar(255) NOT NULL UNIQUE,
+    "name" varchar(255),
+    "role" varchar(16) NOT NULL CHECK ("role" IN ('admin', 'organizer', 'gate_staff')),
+    "password_hash" varchar(255) NOT NULL,
+    "created_at" int,
+    "updated_at" int
+);
+
+CREATE TABLE "api_key" (
+    "id" serial PRIMARY KEY,
+    "name" varchar(255) NOT NULL,
+    "role" varchar(16) NOT NULL CHECK ("role" IN ('admin', 'organizer', 'gate_staff')),
+    "key_hash" varchar(64) NOT NULL UNIQUE,
+    "revoked_at" int,
+    "created_at" int,
+    "updated_at" int
+);
@@ -0,0 +1,11 @@
+ALTER TABLE "api_key"
+    DROP CONSTRAINT IF EXISTS "api_key_organizer_check",
+    DROP COLUMN IF EXISTS "organizer_id";
+
+ALTER TABLE "staff"
+    DROP CONSTRAINT IF EXISTS "staff_organizer_check",
+    DROP COLUMN IF EXISTS "organizer_id";
+
+ALTER TABLE "concert" DROP COLUMN IF EXISTS "organizer_id";
+
+DROP TABLE IF EXISTS organizer;
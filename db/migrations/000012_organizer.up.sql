@@ -0,0 +1,21 @@
+CREATE TABLE "organizer" (
+    "id" serial PRIMARY KEY,
+    "name" varchar(255) NOT NULL UNIQUE,
+    "created_at" int,
+    "updated_at" int
+);
+
+ALTER TABLE "concert" ADD COLUMN "organizer_id" integer;
+
+ALTER TABLE "concert" ADD FOREIGN KEY ("organizer_id") REFERENCES "organizer" ("id");
+
+CREATE INDEX ON "concert" ("organizer_id");
+
+-- Organizer accounts and keys act for exactly one organizer, admins for all of them.
+ALTER TABLE "staff"
+    ADD COLUMN "organizer_id" integer REFERENCES "organizer" ("id"),
+    ADD CONSTRAINT "staff_organizer_check" CHECK ("role" <> 'organizer' OR "organizer_id" IS NOT NULL);
+
+ALTER TABLE "api_key"
+    ADD COLUMN "organizer_id" integer REFERENCES "organizer" ("id"),
+    ADD CONSTRAINT "api_key_organizer_check" CHECK ("role" <> 'organizer' OR "organizer_id" IS NOT NULL);
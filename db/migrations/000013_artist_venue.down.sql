@@ -0,0 +1,7 @@
+ALTER TABLE "concert" DROP CONSTRAINT IF EXISTS "concert_venue_id_fkey";
+
+ALTER TABLE "concert" DROP CONSTRAINT IF EXISTS "concert_artist_id_fkey";
+
+DROP TABLE IF EXISTS venue;
+
+DROP TABLE IF EXISTS artist;
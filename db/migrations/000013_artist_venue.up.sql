@@ -0,0 +1,45 @@
+CREATE TABLE "artist" (
+    "id" serial PRIMARY KEY,
+    "name" varchar(255) NOT NULL,
+    "genre" varchar(255),
+    "created_at" int,
+    "updated_at" int
+);
+
+CREATE TABLE "venue" (
+    "id" serial PRIMARY KEY,
+    "name" varchar(255) NOT NULL,
+    "address" varchar(255),
+    "city" varchar(255),
+    "created_at" int,
+    "updated_at" int
+);
+
+-- Existing concerts keep their references, the placeholders can be renamed afterwards.
+INSERT INTO "artist" ("id", "name", "created_at", "updated_at")
+SELECT DISTINCT "artist_id", 'Artist ' || "artist_id",
+    extract(epoch FROM now())::int, extract(epoch FROM now())::int
+FROM "concert"
+WHERE "artist_id" IS NOT NULL AND "artist_id" > 0;
+
+INSERT INTO "venue" ("id", "name", "created_at", "updated_at")
+SELECT DISTINCT "venue_id", 'Venue ' || "venue_id",
+    extract(epoch FROM now())::int, extract(epoch FROM now())::int
+FROM "concert"
+WHERE "venue_id" IS NOT NULL AND "venue_id" > 0;
+
+SELECT setval(pg_get_serial_sequence('artist', 'id'), COALESCE(MAX("id"), 0) + 1, false) FROM "artist";
+
+SELECT setval(pg_get_serial_sequence('venue', 'id'), COALESCE(MAX("id"), 0) + 1, false) FROM "venue";
+
+UPDATE "concert" SET "artist_id" = NULL WHERE "artist_id" <= 0;
+
+UPDATE "concert" SET "venue_id" = NULL WHERE "venue_id" <= 0;
+
+ALTER TABLE "concert" ADD FOREIGN KEY ("artist_id") REFERENCES "artist" ("id");
+
+ALTER TABLE "concert" ADD FOREIGN KEY ("venue_id") REFERENCES "venue" ("id");
+
+CREATE INDEX ON "concert" ("artist_id");
+
+CREATE INDEX ON "concert" ("venue_id");
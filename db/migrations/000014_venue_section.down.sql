@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ticket_category_section;
+
+DROP TABLE IF EXISTS venue_section;
+
+ALTER TABLE "venue" DROP COLUMN IF EXISTS "capacity";
@@ -0,0 +1,21 @@
+-- Zero leaves the capacity of a venue unknown and unenforced.
+ALTER TABLE "venue" ADD COLUMN "capacity" int NOT NULL DEFAULT 0 CHECK ("capacity" >= 0);
+
+CREATE TABLE "venue_section" (
+    "id" serial PRIMARY KEY,
+    "venue_id" integer NOT NULL REFERENCES "venue" ("id") ON DELETE CASCADE,
+    "name" varchar(255) NOT NULL,
+    "capacity" int NOT NULL CHECK ("capacity" > 0),
+    "created_at" int,
+    "updated_at" int,
+    UNIQUE ("venue_id", "name")
+);
+
+-- Sections a ticket category sells seats of, none for general admission.
+CREATE TABLE "ticket_category_section" (
+    "ticket_category_id" integer NOT NULL REFERENCES "ticket_category" ("id") ON DELETE CASCADE,
+    "section_id" integer NOT NULL REFERENCES "venue_section" ("id"),
+    PRIMARY KEY ("ticket_category_id", "section_id")
+);
+
+CREATE INDEX ON "ticket_category_section" ("section_id");
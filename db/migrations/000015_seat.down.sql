@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "ticket_seat_unique";
+
+ALTER TABLE "ticket" DROP COLUMN IF EXISTS "seat_id";
+
+DROP TABLE IF EXISTS seat;
@@ -0,0 +1,17 @@
+CREATE TABLE "seat" (
+    "id" serial PRIMARY KEY,
+    "section_id" integer NOT NULL REFERENCES "venue_section" ("id") ON DELETE CASCADE,
+    "row" varchar(16) NOT NULL,
+    "number" int NOT NULL CHECK ("number" > 0),
+    "created_at" int,
+    "updated_at" int,
+    UNIQUE ("section_id", "row", "number")
+);
+
+ALTER TABLE "ticket" ADD COLUMN "seat_id" integer;
+
+ALTER TABLE "ticket" ADD FOREIGN KEY ("seat_id") REFERENCES "seat" ("id");
+
+-- A seat holds at most one live ticket per concert, cancelled and refunded tickets free it.
+CREATE UNIQUE INDEX "ticket_seat_unique" ON "ticket" ("concert_id", "seat_id")
+    WHERE "seat_id" IS NOT NULL AND "status" IN ('reserved', 'issued', 'transferred', 'checked_in');
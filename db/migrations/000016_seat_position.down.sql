@@ -0,0 +1,3 @@
+ALTER TABLE "seat" DROP COLUMN IF EXISTS "row_rank";
+
+ALTER TABLE "venue_section" DROP COLUMN IF EXISTS "priority";
@@ -0,0 +1,14 @@
+-- 1 is the best section, 0 leaves the section unranked behind the ranked ones.
+ALTER TABLE "venue_section" ADD COLUMN "priority" int NOT NULL DEFAULT 0 CHECK ("priority" >= 0);
+
+-- Rows counted from the stage, 1 being the front row.
+ALTER TABLE "seat" ADD COLUMN "row_rank" int NOT NULL DEFAULT 1 CHECK ("row_rank" > 0);
+
+-- Existing rows are assumed to be named front to back.
+UPDATE "seat" s
+SET "row_rank" = ranked.rank
+FROM (
+    SELECT "id", DENSE_RANK() OVER (PARTITION BY "section_id" ORDER BY "row") AS rank
+    FROM "seat"
+) ranked
+WHERE ranked."id" = s."id";
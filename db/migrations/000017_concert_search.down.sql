@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "concert_name_search";
+
+DROP INDEX IF EXISTS "concert_date_id_idx";
+
+DROP INDEX IF EXISTS "concert_name_id_idx";
@@ -0,0 +1,6 @@
+-- Names are searched as written, the simple configuration does not stem them.
+CREATE INDEX "concert_name_search" ON "concert" USING GIN (to_tsvector('simple', "name"));
+
+CREATE INDEX ON "concert" ("date", "id");
+
+CREATE INDEX ON "concert" ("name", "id");
@@ -0,0 +1,6 @@
+-- The customer who made the reservation, the only one who can confirm or release it.
+ALTER TABLE "reservation" ADD COLUMN "customer_id" integer;
+
+ALTER TABLE "reservation" ADD FOREIGN KEY ("customer_id") REFERENCES "customer" ("id");
+
+CREATE INDEX ON "reservation" ("customer_id");
@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/hendrywilliam/gate-keeper/queries"
+
+type CreateArtistRequest struct {
+	Name  string `json:"name"`
+	Genre string `json:"genre"`
+}
+
+type UpdateArtistRequest struct {
+	ID    queries.ArtistID `json:"id"`
+	Name  string           `json:"name"`
+	Genre string           `json:"genre"`
+}
+
+type DeleteArtistRequest struct {
+	ID queries.ArtistID `json:"id"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+type IssueTokenRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type CreateStaffRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	// admin, organizer or gate_staff.
+	Role string `json:"role"`
+	// Required for organizers.
+	OrganizerID int `json:"organizer_id"`
+}
+
+type CreateAPIKeyRequest struct {
+	// What the key is for, e.g. the name of the service using it.
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Required for organizers.
+	OrganizerID int `json:"organizer_id"`
+}
+
+type RevokeAPIKeyRequest struct {
+	ID int `json:"id"`
+}
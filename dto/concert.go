@@ -10,6 +10,8 @@ type CreateConcertRequest struct {
 	Date     int    `json:"date"`
 	VenueID  int    `json:"venue_id"`
 	Limit    int    `json:"limit"`
+	// Only read for admins, organizers always create their own concerts.
+	OrganizerID int `json:"organizer_id"`
 }
 
 type DeleteConcertRequest struct {
@@ -26,3 +28,28 @@ type UpdateConcertRequest struct {
 	CreatedAt int               `json:"created_at"`
 	UpdatedAt int               `json:"updated_at"`
 }
+
+type ListConcertSeatsRequest struct {
+	ConcertID queries.ConcertID `query:"concert_id"`
+	// Optional, limits the seats to one section.
+	SectionID queries.VenueSectionID `query:"section_id"`
+}
+
+type SearchConcertsRequest struct {
+	// Full-text search on the concert name.
+	Query string `query:"q"`
+	// Date range using Unix epoch, both included.
+	From      int              `query:"from"`
+	To        int              `query:"to"`
+	ArtistID  queries.ArtistID `query:"artist_id"`
+	VenueID   queries.VenueID  `query:"venue_id"`
+	Available bool             `query:"available"`
+	// date, -date, name or -name.
+	Sort   string `query:"sort"`
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+type GetConcertRequest struct {
+	ID queries.ConcertID `uri:"id"`
+}
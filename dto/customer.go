@@ -0,0 +1,7 @@
+package dto
+
+type RegisterCustomerRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
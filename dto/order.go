@@ -0,0 +1,13 @@
+package dto
+
+type OrderItem struct {
+	TicketCategoryID int `json:"ticket_category"`
+	Quantity         int `json:"quantity"`
+}
+
+type CreateOrderRequest struct {
+	ConcertID int `json:"concert_id"`
+	// Only read for organizers and admins, customers always order for themselves.
+	CustomerEmail string      `json:"customer_email"`
+	Items         []OrderItem `json:"items"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type CreateOrganizerRequest struct {
+	Name string `json:"name"`
+}
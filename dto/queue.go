@@ -0,0 +1,22 @@
+package dto
+
+type JoinQueueRequest struct {
+	ConcertID int `json:"concert_id"`
+}
+
+type QueuePositionRequest struct {
+	ConcertID int    `query:"concert_id"`
+	Token     string `query:"token"`
+}
+
+type OpenQueueRequest struct {
+	ConcertID int `json:"concert_id"`
+	// Users admitted per tick.
+	BatchSize int `json:"batch_size"`
+	// Seconds an admitted user has to buy.
+	AdmissionTTL int `json:"admission_ttl"`
+}
+
+type QueueAdminRequest struct {
+	ConcertID int `json:"concert_id"`
+}
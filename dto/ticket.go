@@ -3,8 +3,17 @@ package dto
 // Date time using Unix Epoch.
 
 type BuyTicketRequest struct {
-	ConcertID        int `json:"concert_id"`
-	TicketCategoryID int `json:"ticket_category"`
+	ConcertID        int    `json:"concert_id"`
+	TicketCategoryID int    `json:"ticket_category"`
+	HolderName       string `json:"holder_name"`
+	HolderEmail      string `json:"holder_email"`
+	// Picks the seat of a reserved seating category, the best seats are allocated when it is left out.
+	SeatID int `json:"seat_id"`
+	// Number of tickets, one when left out. A chosen seat buys a single ticket.
+	Quantity int `json:"quantity"`
+	// Accept allocated seats that are not all next to each other
+	// when no row has room for the whole group.
+	AllowSplit bool `json:"allow_split"`
 }
 
 type GetTicketRequest struct {
@@ -14,3 +23,76 @@ type GetTicketRequest struct {
 type DeleteTicketRequest struct {
 	ID int `json:"id"`
 }
+
+type ReserveTicketRequest struct {
+	ConcertID        int    `json:"concert_id"`
+	TicketCategoryID int    `json:"ticket_category"`
+	Quantity         int    `json:"quantity"`
+	HolderName       string `json:"holder_name"`
+	HolderEmail      string `json:"holder_email"`
+	// One seat per ticket for reserved seating categories.
+	SeatIDs []int `json:"seat_ids"`
+}
+
+type ConfirmReservationRequest struct {
+	ID int `json:"id"`
+}
+
+type ReleaseReservationRequest struct {
+	ID int `json:"id"`
+}
+
+type CheckInTicketRequest struct {
+	SerialNumber string `json:"serial_number"`
+	ConcertID    int    `json:"concert_id"`
+	// Optional name of the gate doing the scan.
+	Gate string `json:"gate"`
+}
+
+type OfflineScan struct {
+	Token string `json:"token"`
+	// Unix epoch of the scan at the gate.
+	ScannedAt int `json:"scanned_at"`
+}
+
+type SyncCheckInsRequest struct {
+	ConcertID int           `json:"concert_id"`
+	Gate      string        `json:"gate"`
+	Scans     []OfflineScan `json:"scans"`
+}
+
+type GetTicketCodeRequest struct {
+	ID int `query:"id"`
+	// qr (default) or code128.
+	Symbology string `query:"symbology"`
+	// png (default) or svg.
+	Format string `query:"format"`
+	// token (default) or serial_number.
+	Content string `query:"content"`
+	// Width in pixels.
+	Size int `query:"size"`
+}
+
+type GetTicketPDFRequest struct {
+	ID int `query:"id"`
+}
+
+type GetConcertTicketsPDFRequest struct {
+	ConcertID int `query:"concert_id"`
+}
+
+type InitiateTicketTransferRequest struct {
+	TicketID       int    `json:"ticket_id"`
+	RecipientEmail string `json:"recipient_email"`
+}
+
+type AcceptTicketTransferRequest struct {
+	TransferID int    `json:"transfer_id"`
+	Code       string `json:"code"`
+	// Name printed on the ticket, defaults to the name of the account.
+	RecipientName string `json:"recipient_name"`
+}
+
+type CancelTicketTransferRequest struct {
+	TransferID int `json:"transfer_id"`
+}
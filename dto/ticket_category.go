@@ -6,6 +6,9 @@ type CreateTicketCategoryRequest struct {
 	Price       float64 `json:"price"`
 	StartDate   int     `json:"start_date"`
 	EndDate     int     `json:"end_date"`
+	Capacity    int     `json:"capacity"`
+	// Venue sections the seats come from, none for general admission.
+	SectionIDs []int `json:"section_ids"`
 }
 
 type UpdateTicketCategoryRequest struct {
@@ -15,8 +18,15 @@ type UpdateTicketCategoryRequest struct {
 	Price       float64 `json:"price"`
 	StartDate   int     `json:"start_date"`
 	EndDate     int     `json:"end_date"`
+	Capacity    int     `json:"capacity"`
+	// Venue sections the seats come from, none for general admission.
+	SectionIDs []int `json:"section_ids"`
 }
 
 type DeleteTicketCategoryRequest struct {
 	ID int `json:"id"`
 }
+
+type ListTicketCategoriesRequest struct {
+	ConcertID int `query:"concert_id"`
+}
@@ -0,0 +1,67 @@
+package dto
+
+import "github.com/hendrywilliam/gate-keeper/queries"
+
+type CreateVenueRequest struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	City    string `json:"city"`
+	// Zero leaves the capacity unenforced.
+	Capacity int `json:"capacity"`
+}
+
+type UpdateVenueRequest struct {
+	ID      queries.VenueID `json:"id"`
+	Name    string          `json:"name"`
+	Address string          `json:"address"`
+	City    string          `json:"city"`
+	// Zero leaves the capacity unchanged.
+	Capacity int `json:"capacity"`
+}
+
+type DeleteVenueRequest struct {
+	ID queries.VenueID `json:"id"`
+}
+
+type CreateVenueSectionRequest struct {
+	VenueID  queries.VenueID `json:"venue_id"`
+	Name     string          `json:"name"`
+	Capacity int             `json:"capacity"`
+	// 1 is the best section, zero leaves it unranked.
+	Priority int `json:"priority"`
+}
+
+type UpdateVenueSectionRequest struct {
+	ID       queries.VenueSectionID `json:"id"`
+	Name     string                 `json:"name"`
+	Capacity int                    `json:"capacity"`
+	// Zero leaves the priority unchanged.
+	Priority int `json:"priority"`
+}
+
+type DeleteVenueSectionRequest struct {
+	ID queries.VenueSectionID `json:"id"`
+}
+
+type ListVenueSectionsRequest struct {
+	VenueID queries.VenueID `query:"venue_id"`
+}
+
+type CreateSeatsRequest struct {
+	SectionID queries.VenueSectionID `json:"section_id"`
+	Row       string                 `json:"row"`
+	// Rows counted from the stage, 1 being the front row.
+	// Zero keeps the rank of an existing row or puts a new row behind the others.
+	RowRank int `json:"row_rank"`
+	// Seat numbers from through to, both included.
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type ListSeatsRequest struct {
+	SectionID queries.VenueSectionID `query:"section_id"`
+}
+
+type DeleteSeatRequest struct {
+	ID queries.SeatID `json:"id"`
+}
@@ -5,12 +5,16 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/auth"
 	cfg "github.com/hendrywilliam/gate-keeper/config"
 	"github.com/hendrywilliam/gate-keeper/controllers"
+	"github.com/hendrywilliam/gate-keeper/middleware"
 	"github.com/hendrywilliam/gate-keeper/queries"
 	"github.com/hendrywilliam/gate-keeper/utils"
+	"github.com/hendrywilliam/gate-keeper/waitroom"
 	"github.com/joho/godotenv"
 )
 
@@ -34,30 +38,190 @@ func main() {
 	logger := slog.New(logHandler)
 	slog.SetDefault(logger)
 	app := fiber.New()
-	mutex := cfg.NewMutex()
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+	redis := cfg.NewRedis(redisAddr)
+	lockPolicy, err := cfg.NewLockPolicy()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	locker := cfg.NewLocker(redis, lockPolicy)
 	db, err := cfg.NewPg(context.Background(), os.Getenv("DATABASE_URL"))
 	if err != nil {
 		slog.Error("failed to open pg")
 		os.Exit(1)
 	}
+	strategy, err := cfg.NewPurchaseStrategy()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	slog.Info("purchase strategy selected", slog.String("strategy", string(strategy)))
+	room := waitroom.NewWaitRoom(redis, logger)
+	admitInterval, err := cfg.DurationEnv("WAITROOM_ADMIT_INTERVAL", time.Second)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	go room.Run(context.Background(), admitInterval)
+	reservationTTL, err := cfg.DurationEnv("RESERVATION_TTL", 10*time.Minute)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	sweepInterval, err := cfg.DurationEnv("RESERVATION_SWEEP_INTERVAL", 30*time.Second)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	orderLimits, err := cfg.NewOrderLimits()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	refundPolicy, err := cfg.NewRefundPolicy()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	signer, err := cfg.NewTicketSigner()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	tokenGrace, err := cfg.DurationEnv("TICKET_TOKEN_GRACE", 24*time.Hour)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	transferTTL, err := cfg.DurationEnv("TICKET_TRANSFER_TTL", 72*time.Hour)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	idempotencyTTL, err := cfg.DurationEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	location, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	idempotency := middleware.NewIdempotency(redis, logger, idempotencyTTL)
 	// Move to heap (as long live object).
 	allQs := queries.NewQueries(db)
+	issuer, err := cfg.NewTokenIssuer()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	authn := middleware.NewAuth(issuer, &allQs, logger)
+
+	concertCtrl := controllers.NewConcertController(locker, &allQs, logger)
+	ticketCtrl := controllers.NewTicketController(locker, &allQs, logger, strategy, room, reservationTTL, refundPolicy, signer, tokenGrace, transferTTL, orderLimits, location)
+	tcatCtrl := controllers.NewTicketCategoryController(locker, &allQs, logger)
+	queueCtrl := controllers.NewQueueController(room, &allQs, logger, admitInterval)
+	orderCtrl := controllers.NewOrderController(&allQs, logger, room, orderLimits, signer, tokenGrace)
+	customerCtrl := controllers.NewCustomerController(&allQs, logger)
+	authCtrl := controllers.NewAuthController(&allQs, logger, issuer)
+	organizerCtrl := controllers.NewOrganizerController(&allQs, logger)
+	artistCtrl := controllers.NewArtistController(&allQs, logger)
+	venueCtrl := controllers.NewVenueController(&allQs, logger)
+
+	go ticketCtrl.SweepReservations(context.Background(), sweepInterval)
+
+	if email, password := os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_PASSWORD"); email != "" && password != "" {
+		if err := authCtrl.EnsureAdmin(context.Background(), email, password); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	app.Use(authn.Handler)
+
+	admin := middleware.RequireRole(auth.RoleAdmin)
+	organizer := middleware.RequireRole(auth.RoleOrganizer, auth.RoleAdmin)
+	gateStaff := middleware.RequireRole(auth.RoleGateStaff, auth.RoleAdmin)
+	customer := middleware.RequireRole(auth.RoleCustomer)
+	// Box offices sell on behalf of customers.
+	seller := middleware.RequireRole(auth.RoleCustomer, auth.RoleOrganizer, auth.RoleAdmin)
+
+	// Anyone.
+	app.Post("/auth/token", authCtrl.IssueCustomerToken)
+	app.Post("/auth/staff/token", authCtrl.IssueStaffToken)
+	app.Post("/customer", customerCtrl.RegisterCustomer)
+	app.Get("/ticket/public-key", ticketCtrl.GetTicketPublicKey)
+	app.Get("/ticket-category", tcatCtrl.ListTicketCategories)
+	app.Get("/ticket-category/available", tcatCtrl.ListPurchasableTicketCategories)
+	app.Post("/queue", queueCtrl.JoinQueue)
+	app.Get("/queue", queueCtrl.GetQueuePosition)
+	app.Get("/queue/stream", queueCtrl.StreamQueuePosition)
+	app.Get("/artist", artistCtrl.ListArtists)
+	app.Get("/venue", venueCtrl.ListVenues)
+	app.Get("/venue/section", venueCtrl.ListVenueSections)
+	app.Get("/venue/seat", venueCtrl.ListSeats)
+	app.Get("/concert/seat", concertCtrl.ListConcertSeats)
+	app.Get("/concerts", concertCtrl.SearchConcerts)
+	app.Get("/concerts/:id", concertCtrl.GetConcert)
 
-	concertCtrl := controllers.NewConcertController(mutex, &allQs, logger)
-	ticketCtrl := controllers.NewTicketController(mutex, &allQs, logger)
-	tcatCtrl := controllers.NewTicketCategoryController(mutex, &allQs, logger)
+	// Admins.
+	app.Post("/staff", authCtrl.CreateStaff, admin)
+	app.Post("/api-key", authCtrl.CreateAPIKey, admin)
+	app.Delete("/api-key", authCtrl.RevokeAPIKey, admin)
+	app.Post("/organizer", organizerCtrl.CreateOrganizer, admin)
+	app.Get("/organizer", organizerCtrl.ListOrganizers, admin)
+	app.Delete("/artist", artistCtrl.DeleteArtist, admin)
+	app.Delete("/venue", venueCtrl.DeleteVenue, admin)
 
-	app.Post("/concert", concertCtrl.CreateConcert)
-	app.Delete("/concert", concertCtrl.DeleteConcert)
-	app.Put("/concert", concertCtrl.UpdateConcert)
+	// Organizers.
+	app.Post("/artist", artistCtrl.CreateArtist, organizer)
+	app.Put("/artist", artistCtrl.UpdateArtist, organizer)
+	app.Post("/venue", venueCtrl.CreateVenue, organizer)
+	app.Put("/venue", venueCtrl.UpdateVenue, organizer)
+	app.Post("/venue/section", venueCtrl.CreateVenueSection, organizer)
+	app.Put("/venue/section", venueCtrl.UpdateVenueSection, organizer)
+	app.Delete("/venue/section", venueCtrl.DeleteVenueSection, organizer)
+	app.Post("/venue/seat", venueCtrl.CreateSeats, organizer)
+	app.Delete("/venue/seat", venueCtrl.DeleteSeat, organizer)
+	app.Get("/concert", concertCtrl.ListConcerts, organizer)
+	app.Post("/concert", concertCtrl.CreateConcert, organizer)
+	app.Delete("/concert", concertCtrl.DeleteConcert, organizer)
+	app.Put("/concert", concertCtrl.UpdateConcert, organizer)
+	app.Post("/ticket-category", tcatCtrl.CreateTicketCategory, organizer)
+	app.Put("/ticket-category", tcatCtrl.UpdateTicketCategory, organizer)
+	app.Delete("/ticket-category", tcatCtrl.DeleteTicketCategory, organizer)
+	app.Get("/ticket/pdf/concert", ticketCtrl.GetConcertTicketsPDF, organizer)
+	app.Post("/queue/open", queueCtrl.OpenQueue, organizer)
+	app.Post("/queue/pause", queueCtrl.PauseQueue, organizer)
+	app.Post("/queue/resume", queueCtrl.ResumeQueue, organizer)
+	app.Post("/queue/drain", queueCtrl.DrainQueue, organizer)
 
-	app.Post("/ticket", ticketCtrl.BuyTicket)
-	app.Delete("/ticket", ticketCtrl.CancelTicket)
-	app.Get("/ticket", ticketCtrl.GetTicket)
+	// Gate staff.
+	app.Post("/ticket/check-in", ticketCtrl.CheckInTicket, gateStaff)
+	app.Post("/ticket/check-in/sync", ticketCtrl.SyncCheckIns, gateStaff)
 
-	app.Post("/ticket-category", tcatCtrl.CreateTicketCategory)
-	app.Put("/ticket-category", tcatCtrl.UpdateTicketCategory)
-	app.Delete("/ticket-category", tcatCtrl.DeleteTicketCategory)
+	// Customers.
+	app.Get("/customer", customerCtrl.GetCurrentCustomer, customer)
+	app.Get("/customer/tickets", ticketCtrl.ListCustomerTickets, customer)
+	app.Post("/ticket", ticketCtrl.BuyTicket, customer, idempotency.Handler)
+	app.Delete("/ticket", ticketCtrl.CancelTicket, customer, idempotency.Handler)
+	app.Get("/ticket", ticketCtrl.GetTicket, customer)
+	app.Get("/ticket/history", ticketCtrl.GetTicketHistory, customer)
+	app.Get("/ticket/code", ticketCtrl.GetTicketCode, customer)
+	app.Get("/ticket/pdf", ticketCtrl.GetTicketPDF, customer)
+	app.Get("/ticket/transfer", ticketCtrl.ListTicketTransfers, customer)
+	app.Post("/ticket/transfer", ticketCtrl.InitiateTicketTransfer, customer, idempotency.Handler)
+	app.Post("/ticket/transfer/accept", ticketCtrl.AcceptTicketTransfer, customer, idempotency.Handler)
+	app.Delete("/ticket/transfer", ticketCtrl.CancelTicketTransfer, customer)
+	app.Post("/ticket/reservation", ticketCtrl.ReserveTicket, customer)
+	app.Post("/ticket/reservation/confirm", ticketCtrl.ConfirmReservation, customer)
+	app.Delete("/ticket/reservation", ticketCtrl.ReleaseReservation, customer)
+	app.Post("/order", orderCtrl.CreateOrder, seller)
 
 	app.Listen(":8080")
 }
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/hendrywilliam/gate-keeper/auth"
+	"github.com/hendrywilliam/gate-keeper/queries"
+)
+
+const HeaderAPIKey = "X-API-Key"
+
+const principalKey = "principal"
+
+// Auth works out who a request is made by, from a bearer JWT or an API key.
+// Requests without credentials go through anonymously, RequireRole turns them away where needed.
+type Auth struct {
+	issuer *auth.Issuer
+	q      *queries.Queries
+	log    *slog.Logger
+}
+
+func NewAuth(issuer *auth.Issuer, q *queries.Queries, log *slog.Logger) *Auth {
+	return &Auth{
+		issuer: issuer,
+		q:      q,
+		log:    log,
+	}
+}
+
+func (a *Auth) Handler(c fiber.Ctx) error {
+	if key := c.Get(HeaderAPIKey); key != "" {
+		apiKey, err := a.q.APIKey.GetAPIKeyByHash(c.Context(), auth.HashAPIKey(key))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return unauthorized(c, "invalid API key")
+			}
+			a.log.Error(err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"code":    http.StatusInternalServerError,
+				"message": "internal server error",
+			})
+		}
+		c.Locals(principalKey, auth.Principal{
+			Kind:        auth.KindAPIKey,
+			ID:          apiKey.ID,
+			Role:        apiKey.Role,
+			OrganizerID: apiKey.OrganizerID,
+		})
+		return c.Next()
+	}
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return c.Next()
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return unauthorized(c, "expected a bearer token")
+	}
+	principal, err := a.issuer.Parse(token)
+	if err != nil {
+		return unauthorized(c, "invalid or expired token")
+	}
+	c.Locals(principalKey, principal)
+	return c.Next()
+}
+
+// RequireRole lets through only principals with one of the given roles.
+func RequireRole(roles ...auth.Role) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		principal, ok := CurrentPrincipal(c)
+		if !ok {
+			return unauthorized(c, "sign in first")
+		}
+		if !slices.Contains(roles, principal.Role) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"code":    http.StatusForbidden,
+				"message": "you are not allowed to do this.",
+			})
+		}
+		return c.Next()
+	}
+}
+
+func CurrentPrincipal(c fiber.Ctx) (auth.Principal, bool) {
+	p, ok := c.Locals(principalKey).(auth.Principal)
+	return p, ok
+}
+
+// CustomerID returns the customer the request acts for, if any.
+func CustomerID(c fiber.Ctx) (int, bool) {
+	p, ok := CurrentPrincipal(c)
+	if !ok || p.Kind != auth.KindCustomer {
+		return 0, false
+	}
+	return p.ID, true
+}
+
+// OrganizerScope returns the organizer whose data the request is limited to,
+// zero for principals that are not bound to an organizer.
+func OrganizerScope(c fiber.Ctx) int {
+	p, ok := CurrentPrincipal(c)
+	if !ok || p.Role != auth.RoleOrganizer {
+		return 0
+	}
+	return p.OrganizerID
+}
+
+func unauthorized(c fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+		"code":    http.StatusUnauthorized,
+		"message": message,
+	})
+}
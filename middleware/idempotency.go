@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+const (
+	idempotencyProcessing = "processing"
+	idempotencyCompleted  = "completed"
+)
+
+// How long a key stays locked while its first request is being handled.
+const idempotencyLockTTL = time.Minute
+
+type idempotencyRecord struct {
+	State       string `json:"state"`
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency replays the stored response when a request is retried with the same Idempotency-Key,
+// so a retried purchase never issues a second ticket.
+type Idempotency struct {
+	redis *redisClient.Client
+	log   *slog.Logger
+	// How long a completed response is kept for replay.
+	ttl time.Duration
+}
+
+func NewIdempotency(redis *redisClient.Client, log *slog.Logger, ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		redis: redis,
+		log:   log,
+		ttl:   ttl,
+	}
+}
+
+func (i *Idempotency) Handler(c fiber.Ctx) error {
+	key := c.Get(HeaderIdempotencyKey)
+	if key == "" {
+		return c.Next()
+	}
+	ctx := c.Context()
+	// Keys are scoped per route, the same key may be used to buy and later to cancel.
+	redisKey := fmt.Sprintf("idempotency:%s:%s:%s", c.Method(), c.Path(), key)
+	// Keys are chosen by clients, two customers may well pick the same one.
+	if customerID, ok := CustomerID(c); ok {
+		redisKey = fmt.Sprintf("idempotency:customer:%d:%s:%s:%s", customerID, c.Method(), c.Path(), key)
+	}
+	sum := sha256.Sum256(c.Body())
+	fingerprint := hex.EncodeToString(sum[:])
+
+	processing, _ := json.Marshal(idempotencyRecord{
+		State:       idempotencyProcessing,
+		Fingerprint: fingerprint,
+	})
+	acquired, err := i.redis.SetNX(ctx, redisKey, processing, idempotencyLockTTL).Result()
+	if err != nil {
+		i.log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if !acquired {
+		return i.replay(c, redisKey, fingerprint)
+	}
+
+	if err := c.Next(); err != nil {
+		i.redis.Del(ctx, redisKey)
+		return err
+	}
+	status := c.Response().StatusCode()
+	// Server errors and rejections the client is told to retry are not final,
+	// let the client try again with the same key.
+	if !isFinalResponse(status) || len(c.Response().Header.Peek(fiber.HeaderRetryAfter)) > 0 {
+		i.redis.Del(ctx, redisKey)
+		return nil
+	}
+	completed, err := json.Marshal(idempotencyRecord{
+		State:       idempotencyCompleted,
+		Fingerprint: fingerprint,
+		Status:      status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Body:        c.Response().Body(),
+	})
+	if err != nil {
+		i.log.Error(err.Error())
+		i.redis.Del(ctx, redisKey)
+		return nil
+	}
+	if err := i.redis.Set(ctx, redisKey, completed, i.ttl).Err(); err != nil {
+		i.log.Error(err.Error())
+	}
+	return nil
+}
+
+// Outcomes that stay the same however often the request is retried.
+// Missing sign-ins, waiting room rejections and rate limits change with time.
+func isFinalResponse(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+func (i *Idempotency) replay(c fiber.Ctx, redisKey string, fingerprint string) error {
+	raw, err := i.redis.Get(c.Context(), redisKey).Bytes()
+	if err != nil {
+		if errors.Is(err, redisClient.Nil) {
+			// The first request failed and released the key in the meantime.
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"code":    http.StatusConflict,
+				"message": "a request with this idempotency key was just processed. try again.",
+			})
+		}
+		i.log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		i.log.Error(err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"code":    http.StatusInternalServerError,
+			"message": "internal server error",
+		})
+	}
+	if record.Fingerprint != fingerprint {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"code":    http.StatusConflict,
+			"message": "idempotency key was already used with a different request body.",
+		})
+	}
+	if record.State == idempotencyProcessing {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"code":    http.StatusConflict,
+			"message": "a request with this idempotency key is still being processed.",
+		})
+	}
+	c.Set(HeaderIdempotentReplayed, "true")
+	c.Set(fiber.HeaderContentType, record.ContentType)
+	return c.Status(record.Status).Send(record.Body)
+}
@@ -0,0 +1,102 @@
+package queries
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type APIKeyID = int
+
+type APIKey struct {
+	ID          APIKeyID `json:"id"`
+	Name        string   `json:"name"`
+	Role        string   `json:"role"`
+	OrganizerID int      `json:"organizer_id,omitempty"`
+	RevokedAt   int      `json:"revoked_at,omitempty"`
+	CreatedAt   int      `json:"created_at,omitempty"`
+	UpdatedAt   int      `json:"updated_at,omitempty"`
+}
+
+func (k APIKey) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", k.ID),
+		slog.String("name", k.Name),
+		slog.String("role", k.Role),
+	)
+}
+
+type APIKeyQueryImpl struct {
+	DB DbTx
+}
+
+type CreateAPIKeyArgs struct {
+	Name        string
+	Role        string
+	OrganizerID OrganizerID
+	KeyHash     string
+}
+
+func (kq *APIKeyQueryImpl) CreateAPIKey(ctx context.Context, args CreateAPIKeyArgs) (APIKey, error) {
+	row := kq.DB.QueryRow(ctx, `
+		INSERT INTO api_key (
+			name,
+			role,
+			organizer_id,
+			key_hash,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, NULLIF($5, 0), $3, $4, $4
+		) RETURNING id, name, role, COALESCE(organizer_id, 0), created_at, updated_at;
+	`, args.Name, args.Role, args.KeyHash, time.Now().Unix(), args.OrganizerID)
+	var k APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Role,
+		&k.OrganizerID,
+		&k.CreatedAt,
+		&k.UpdatedAt,
+	)
+	return k, err
+}
+
+// Only keys that have not been revoked are found.
+func (kq *APIKeyQueryImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	row := kq.DB.QueryRow(ctx, `
+		SELECT id, name, role, COALESCE(organizer_id, 0), created_at, updated_at
+		FROM api_key
+		WHERE key_hash = $1 AND revoked_at IS NULL;
+	`, keyHash)
+	var k APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Role,
+		&k.OrganizerID,
+		&k.CreatedAt,
+		&k.UpdatedAt,
+	)
+	return k, err
+}
+
+func (kq *APIKeyQueryImpl) RevokeAPIKey(ctx context.Context, id APIKeyID) (APIKey, error) {
+	row := kq.DB.QueryRow(ctx, `
+		UPDATE api_key
+		SET revoked_at = $2, updated_at = $2
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING id, name, role, COALESCE(organizer_id, 0), revoked_at, created_at, updated_at;
+	`, id, time.Now().Unix())
+	var k APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Role,
+		&k.OrganizerID,
+		&k.RevokedAt,
+		&k.CreatedAt,
+		&k.UpdatedAt,
+	)
+	return k, err
+}
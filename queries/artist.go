@@ -0,0 +1,152 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type ArtistID = int
+
+var ErrArtistInUse = errors.New("artist has concerts")
+
+type Artist struct {
+	ID        ArtistID `json:"id"`
+	Name      string   `json:"name"`
+	Genre     string   `json:"genre,omitempty"`
+	CreatedAt int      `json:"created_at,omitempty"`
+	UpdatedAt int      `json:"updated_at,omitempty"`
+}
+
+func (a Artist) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", a.ID),
+		slog.String("name", a.Name),
+	)
+}
+
+type ArtistQueryImpl struct {
+	DB DbTx
+}
+
+type CreateArtistArgs struct {
+	Name  string
+	Genre string
+}
+
+func (aq *ArtistQueryImpl) CreateArtist(ctx context.Context, args CreateArtistArgs) (Artist, error) {
+	row := aq.DB.QueryRow(ctx, `
+		INSERT INTO artist (
+			name,
+			genre,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, ''), $3, $3
+		) RETURNING id, name, COALESCE(genre, ''), created_at, updated_at;
+	`, args.Name, args.Genre, time.Now().Unix())
+	var a Artist
+	err := row.Scan(
+		&a.ID,
+		&a.Name,
+		&a.Genre,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	return a, err
+}
+
+func (aq *ArtistQueryImpl) GetArtist(ctx context.Context, id ArtistID) (Artist, error) {
+	row := aq.DB.QueryRow(ctx, `
+		SELECT id, name, COALESCE(genre, ''), created_at, updated_at
+		FROM artist
+		WHERE id = $1;
+	`, id)
+	var a Artist
+	err := row.Scan(
+		&a.ID,
+		&a.Name,
+		&a.Genre,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	return a, err
+}
+
+func (aq *ArtistQueryImpl) ListArtists(ctx context.Context) ([]Artist, error) {
+	rows, err := aq.DB.Query(ctx, `
+		SELECT id, name, COALESCE(genre, ''), created_at, updated_at
+		FROM artist
+		ORDER BY name, id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	artists := []Artist{}
+	for rows.Next() {
+		var a Artist
+		if err := rows.Scan(
+			&a.ID,
+			&a.Name,
+			&a.Genre,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	return artists, rows.Err()
+}
+
+type UpdateArtistArgs struct {
+	ID ArtistID
+	// Empty fields are left unchanged.
+	Name  string
+	Genre string
+}
+
+func (aq *ArtistQueryImpl) UpdateArtist(ctx context.Context, args UpdateArtistArgs) (Artist, error) {
+	row := aq.DB.QueryRow(ctx, `
+		UPDATE artist
+		SET name = COALESCE(NULLIF($2, ''), name),
+			genre = COALESCE(NULLIF($3, ''), genre),
+			updated_at = $4
+		WHERE id = $1
+		RETURNING id, name, COALESCE(genre, ''), created_at, updated_at;
+	`, args.ID, args.Name, args.Genre, time.Now().Unix())
+	var a Artist
+	err := row.Scan(
+		&a.ID,
+		&a.Name,
+		&a.Genre,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	return a, err
+}
+
+// Returns ErrArtistInUse while any concert still points at the artist.
+func (aq *ArtistQueryImpl) DeleteArtist(ctx context.Context, id ArtistID) (Artist, error) {
+	row := aq.DB.QueryRow(ctx, `
+		DELETE FROM artist
+		WHERE id = $1
+			AND NOT EXISTS (SELECT 1 FROM concert WHERE artist_id = $1)
+		RETURNING id, name;
+	`, id)
+	var a Artist
+	err := row.Scan(
+		&a.ID,
+		&a.Name,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := aq.GetArtist(ctx, id); err != nil {
+			return a, err
+		}
+		return a, ErrArtistInUse
+	}
+	return a, err
+}
@@ -3,6 +3,8 @@ package queries
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -11,15 +13,26 @@ import (
 
 type ConcertID = int
 
+var (
+	ErrConcertLimitReached = errors.New("concert limit reached")
+	ErrArtistNotFound      = errors.New("artist not found")
+	ErrVenueNotFound       = errors.New("venue not found")
+	ErrConcertOverCapacity = errors.New("concert limit exceeds the venue capacity")
+	ErrConcertSectionsUsed = errors.New("concert has ticket categories mapped to sections of its venue")
+)
+
 type Concert struct {
-	ID        ConcertID `json:"id,omitempty"`
-	Name      string    `json:"concert,omitempty"`
-	ArtistID  int       `json:"artist_id,omitempty"`
-	Date      int       `json:"date,omitempty"`
-	VenueID   int       `json:"venue_id,omitempty"`
-	Limit     int       `json:"limit,omitempty"`
-	CreatedAt int       `json:"created_at,omitempty"`
-	UpdatedAt int       `json:"updated_at,omitempty"`
+	ID          ConcertID   `json:"id,omitempty"`
+	Name        string      `json:"concert,omitempty"`
+	ArtistID    int         `json:"artist_id,omitempty"`
+	Date        int         `json:"date,omitempty"`
+	VenueID     int         `json:"venue_id,omitempty"`
+	Limit       int         `json:"limit,omitempty"`
+	OrganizerID OrganizerID `json:"organizer_id,omitempty"`
+	Artist      *Artist     `json:"artist,omitempty"`
+	Venue       *Venue      `json:"venue,omitempty"`
+	CreatedAt   int         `json:"created_at,omitempty"`
+	UpdatedAt   int         `json:"updated_at,omitempty"`
 }
 
 func (c Concert) LogValue() slog.Value {
@@ -35,37 +48,138 @@ type ConcertQueryImpl struct {
 	DB DbTx
 }
 
-type CreateConcertQueryArgs struct {
-	Name     string
-	ArtistID int
-	VenueID  int
-	Date     int
-	Limit    int
-}
+// Selected from concert c joined with artist a and venue v.
+const concertColumns = `c.id, c.name, COALESCE(c.artist_id, 0), COALESCE(c.venue_id, 0), c.date, c."limit",
+	COALESCE(c.organizer_id, 0), c.created_at, c.updated_at,
+	COALESCE(a.name, ''), COALESCE(a.genre, ''),
+	COALESCE(v.name, ''), COALESCE(v.address, ''), COALESCE(v.city, '')`
 
-func (cq *ConcertQueryImpl) GetConcert(ctx context.Context, ID ConcertID) (Concert, error) {
-	row := cq.DB.QueryRow(ctx, `
-		SELECT
-			name,
-			artist_id,
-			venue_id,
-			date,
-			"limit"
-		FROM concert
-		WHERE id = $1;
-	`, ID)
+const concertJoins = `concert c
+	LEFT JOIN artist a ON a.id = c.artist_id
+	LEFT JOIN venue v ON v.id = c.venue_id`
+
+func scanConcert(row interface{ Scan(dest ...any) error }) (Concert, error) {
 	var t Concert
+	var artist Artist
+	var venue Venue
 	err := row.Scan(
+		&t.ID,
 		&t.Name,
 		&t.ArtistID,
 		&t.VenueID,
 		&t.Date,
 		&t.Limit,
+		&t.OrganizerID,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&artist.Name,
+		&artist.Genre,
+		&venue.Name,
+		&venue.Address,
+		&venue.City,
 	)
+	if t.ArtistID > 0 {
+		artist.ID = t.ArtistID
+		t.Artist = &artist
+	}
+	if t.VenueID > 0 {
+		venue.ID = t.VenueID
+		t.Venue = &venue
+	}
 	return t, err
 }
 
+// Fails with ErrArtistNotFound or ErrVenueNotFound for references that do not exist.
+// Zero IDs are skipped when optional is set.
+func (cq *ConcertQueryImpl) checkReferences(ctx context.Context, artistID ArtistID, venueID VenueID, optional bool) error {
+	var id int
+	if artistID > 0 || !optional {
+		err := cq.DB.QueryRow(ctx, `SELECT id FROM artist WHERE id = $1;`, artistID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrArtistNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if venueID > 0 || !optional {
+		err := cq.DB.QueryRow(ctx, `SELECT id FROM venue WHERE id = $1;`, venueID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVenueNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fails with ErrConcertOverCapacity when the limit does not fit in the venue.
+func (cq *ConcertQueryImpl) checkVenueCapacity(ctx context.Context, venueID VenueID, limit int) error {
+	var capacity int
+	err := cq.DB.QueryRow(ctx, `SELECT capacity FROM venue WHERE id = $1;`, venueID).Scan(&capacity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVenueNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if capacity > 0 && limit > capacity {
+		return ErrConcertOverCapacity
+	}
+	return nil
+}
+
+type CreateConcertQueryArgs struct {
+	Name        string
+	ArtistID    ArtistID
+	VenueID     VenueID
+	Date        int
+	Limit       int
+	OrganizerID OrganizerID
+}
+
+// The concert comes with its artist and venue.
+func (cq *ConcertQueryImpl) GetConcert(ctx context.Context, ID ConcertID) (Concert, error) {
+	row := cq.DB.QueryRow(ctx, `
+		SELECT `+concertColumns+`
+		FROM `+concertJoins+`
+		WHERE c.id = $1;
+	`, ID)
+	return scanConcert(row)
+}
+
+// Concerts of the organizer, or of every organizer when organizerID is zero.
+func (cq *ConcertQueryImpl) ListConcerts(ctx context.Context, organizerID OrganizerID) ([]Concert, error) {
+	rows, err := cq.DB.Query(ctx, `
+		SELECT `+concertColumns+`
+		FROM `+concertJoins+`
+		WHERE $1 = 0 OR c.organizer_id = $1
+		ORDER BY c.date, c.id;
+	`, organizerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	concerts := []Concert{}
+	for rows.Next() {
+		t, err := scanConcert(rows)
+		if err != nil {
+			return nil, err
+		}
+		concerts = append(concerts, t)
+	}
+	return concerts, rows.Err()
+}
+
+// Both the artist and the venue must exist.
 func (cq *ConcertQueryImpl) CreateConcert(ctx context.Context, args CreateConcertQueryArgs) (Concert, error) {
+	if err := cq.checkReferences(ctx, args.ArtistID, args.VenueID, false); err != nil {
+		return Concert{}, err
+	}
+	if err := cq.checkVenueCapacity(ctx, args.VenueID, args.Limit); err != nil {
+		return Concert{}, err
+	}
 	row := cq.DB.QueryRow(ctx, `
 		INSERT INTO concert (
 			name,
@@ -73,28 +187,27 @@ func (cq *ConcertQueryImpl) CreateConcert(ctx context.Context, args CreateConcer
 			venue_id,
 			date,
 			"limit",
+			organizer_id,
 			created_at,
 			updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7
-		) RETURNING id, name, date, "limit";
-	`, args.Name, args.ArtistID, args.VenueID, args.Date, args.Limit, time.Now().Unix(), time.Now().Unix())
-	var c Concert
-	err := row.Scan(
-		&c.ID,
-		&c.Name,
-		&c.Date,
-		&c.Limit,
-	)
-	return c, err
+			$1, $2, $3, $4, $5, NULLIF($8, 0), $6, $7
+		) RETURNING id;
+	`, args.Name, args.ArtistID, args.VenueID, args.Date, args.Limit, time.Now().Unix(), time.Now().Unix(), args.OrganizerID)
+	var id ConcertID
+	if err := row.Scan(&id); err != nil {
+		return Concert{}, err
+	}
+	return cq.GetConcert(ctx, id)
 }
 
-func (cq *ConcertQueryImpl) DeleteConcert(ctx context.Context, id ConcertID) (Concert, error) {
+// A non-zero organizerID limits the delete to that organizer's concerts.
+func (cq *ConcertQueryImpl) DeleteConcert(ctx context.Context, id ConcertID, organizerID OrganizerID) (Concert, error) {
 	row := cq.DB.QueryRow(ctx, `
 		DELETE FROM concert
-		WHERE id = $1
+		WHERE id = $1 AND ($2 = 0 OR organizer_id = $2)
 		RETURNING id, name;
-	`, id)
+	`, id, organizerID)
 	var c Concert
 	err := row.Scan(
 		&c.ID,
@@ -106,15 +219,51 @@ func (cq *ConcertQueryImpl) DeleteConcert(ctx context.Context, id ConcertID) (Co
 type UpdateConcertArgs struct {
 	ID        ConcertID
 	Name      string
-	ArtistID  int
-	VenueID   int
+	ArtistID  ArtistID
+	VenueID   VenueID
 	Date      int
 	Limit     int
 	CreatedAt int
 	UpdatedAt int
+	// Limits the update to the organizer's concerts, zero for none.
+	OrganizerID OrganizerID
 }
 
+// A new artist or venue must exist and the limit must fit in the venue.
+// Moving to another venue fails with ErrConcertSectionsUsed while categories are mapped to sections of the current one.
 func (cq *ConcertQueryImpl) UpdateConcert(ctx context.Context, args UpdateConcertArgs) (Concert, error) {
+	if err := cq.checkReferences(ctx, args.ArtistID, args.VenueID, true); err != nil {
+		return Concert{}, err
+	}
+	venueID := args.VenueID
+	if venueID > 0 {
+		var mapped bool
+		err := cq.DB.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM ticket_category tc
+				JOIN ticket_category_section tcs ON tcs.ticket_category_id = tc.id
+				JOIN venue_section vs ON vs.id = tcs.section_id
+				WHERE tc.concert_id = $1 AND vs.venue_id <> $2
+			);
+		`, args.ID, venueID).Scan(&mapped)
+		if err != nil {
+			return Concert{}, err
+		}
+		if mapped {
+			return Concert{}, ErrConcertSectionsUsed
+		}
+	} else {
+		current, err := cq.GetConcert(ctx, args.ID)
+		if err != nil {
+			return current, err
+		}
+		venueID = current.VenueID
+	}
+	if venueID > 0 {
+		if err := cq.checkVenueCapacity(ctx, venueID, args.Limit); err != nil {
+			return Concert{}, err
+		}
+	}
 	baseSql := &bytes.Buffer{}
 	var setClauses []string
 	var arguments []interface{}
@@ -132,7 +281,7 @@ func (cq *ConcertQueryImpl) UpdateConcert(ctx context.Context, args UpdateConcer
 		paramIndex++
 	}
 	if args.VenueID > 0 {
-		setClauses = append(setClauses, fmt.Sprintf("artist_id = $%v", paramIndex))
+		setClauses = append(setClauses, fmt.Sprintf("venue_id = $%v", paramIndex))
 		arguments = append(arguments, args.VenueID)
 		paramIndex++
 	}
@@ -151,14 +300,63 @@ func (cq *ConcertQueryImpl) UpdateConcert(ctx context.Context, args UpdateConcer
 	arguments = append(arguments, time.Now().Unix())
 	paramIndex++
 	baseSql.WriteString(fmt.Sprintf("SET %s ", strings.Join(setClauses, ", ")))
-	baseSql.WriteString(fmt.Sprintf("WHERE id = $%v RETURNING id, name;", paramIndex))
+	baseSql.WriteString(fmt.Sprintf("WHERE id = $%v ", paramIndex))
 	arguments = append(arguments, args.ID)
-	fmt.Println(baseSql.String(), arguments)
+	paramIndex++
+	if args.OrganizerID > 0 {
+		baseSql.WriteString(fmt.Sprintf("AND organizer_id = $%v ", paramIndex))
+		arguments = append(arguments, args.OrganizerID)
+		paramIndex++
+	}
+	baseSql.WriteString("RETURNING id;")
 	row := cq.DB.QueryRow(ctx, baseSql.String(), arguments...)
+	var id ConcertID
+	if err := row.Scan(&id); err != nil {
+		return Concert{}, err
+	}
+	return cq.GetConcert(ctx, id)
+}
+
+// Atomically takes quantity seats from the concert limit.
+// Returns ErrConcertLimitReached when there are not enough seats left.
+func (cq *ConcertQueryImpl) DecrementConcertLimit(ctx context.Context, id ConcertID, quantity int) (Concert, error) {
+	row := cq.DB.QueryRow(ctx, `
+		UPDATE concert
+		SET "limit" = "limit" - $2,
+			updated_at = $3
+		WHERE id = $1 AND "limit" >= $2
+		RETURNING id, name, "limit";
+	`, id, quantity, time.Now().Unix())
 	var c Concert
 	err := row.Scan(
 		&c.ID,
 		&c.Name,
+		&c.Limit,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		// Either the concert does not exist or it is sold out.
+		if _, err := cq.GetConcert(ctx, id); err != nil {
+			return c, err
+		}
+		return c, ErrConcertLimitReached
+	}
+	return c, err
+}
+
+// Gives quantity seats back to the concert limit.
+func (cq *ConcertQueryImpl) IncrementConcertLimit(ctx context.Context, id ConcertID, quantity int) (Concert, error) {
+	row := cq.DB.QueryRow(ctx, `
+		UPDATE concert
+		SET "limit" = "limit" + $2,
+			updated_at = $3
+		WHERE id = $1
+		RETURNING id, name, "limit";
+	`, id, quantity, time.Now().Unix())
+	var c Concert
+	err := row.Scan(
+		&c.ID,
+		&c.Name,
+		&c.Limit,
 	)
 	return c, err
 }
@@ -0,0 +1,183 @@
+package queries
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ConcertSort = string
+
+const (
+	ConcertSortDate     ConcertSort = "date"
+	ConcertSortDateDesc ConcertSort = "-date"
+	ConcertSortName     ConcertSort = "name"
+	ConcertSortNameDesc ConcertSort = "-name"
+)
+
+var (
+	ErrInvalidConcertSort   = errors.New("invalid concert sort")
+	ErrInvalidConcertCursor = errors.New("invalid concert cursor")
+)
+
+// Position after the last concert of a page, only valid for the sort it was made with.
+type ConcertCursor struct {
+	Sort ConcertSort `json:"s"`
+	Date int         `json:"d,omitempty"`
+	Name string      `json:"n,omitempty"`
+	ID   ConcertID   `json:"i"`
+}
+
+// Opaque to clients, they pass it back as is to get the next page.
+func (cc ConcertCursor) Encode() string {
+	b, _ := json.Marshal(cc)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeConcertCursor(s string) (ConcertCursor, error) {
+	var cc ConcertCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cc, ErrInvalidConcertCursor
+	}
+	if err := json.Unmarshal(b, &cc); err != nil || cc.ID <= 0 {
+		return cc, ErrInvalidConcertCursor
+	}
+	return cc, nil
+}
+
+type SearchConcertsArgs struct {
+	// Full-text search on the concert name, empty for every concert.
+	Query string
+	// Date range using Unix epoch, both included. Zero leaves that side open.
+	From int
+	To   int
+	// Zero for any artist or venue.
+	ArtistID ArtistID
+	VenueID  VenueID
+	// Only concerts with a category on sale right now that is not sold out.
+	Available bool
+	// ConcertSortDate when empty.
+	Sort  ConcertSort
+	After *ConcertCursor
+	Limit int
+}
+
+type ConcertPage struct {
+	Concerts []Concert `json:"concerts"`
+	// Empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Concerts of every organizer matching the filters, a page at a time using keyset pagination.
+func (cq *ConcertQueryImpl) SearchConcerts(ctx context.Context, args SearchConcertsArgs) (ConcertPage, error) {
+	if args.Sort == "" {
+		args.Sort = ConcertSortDate
+	}
+	var orderBy, keyColumn, keyOp string
+	switch args.Sort {
+	case ConcertSortDate:
+		orderBy, keyColumn, keyOp = "c.date, c.id", "c.date", ">"
+	case ConcertSortDateDesc:
+		orderBy, keyColumn, keyOp = "c.date DESC, c.id DESC", "c.date", "<"
+	case ConcertSortName:
+		orderBy, keyColumn, keyOp = "c.name, c.id", "c.name", ">"
+	case ConcertSortNameDesc:
+		orderBy, keyColumn, keyOp = "c.name DESC, c.id DESC", "c.name", "<"
+	default:
+		return ConcertPage{}, ErrInvalidConcertSort
+	}
+	if args.After != nil && args.After.Sort != args.Sort {
+		return ConcertPage{}, ErrInvalidConcertCursor
+	}
+	baseSql := &bytes.Buffer{}
+	var whereClauses []string
+	var arguments []interface{}
+	paramIndex := 1
+	baseSql.WriteString("SELECT " + concertColumns + " FROM " + concertJoins + " ")
+	if q := strings.TrimSpace(args.Query); q != "" {
+		// Matches the concert_name_search index.
+		whereClauses = append(whereClauses, fmt.Sprintf("to_tsvector('simple', c.name) @@ websearch_to_tsquery('simple', $%v)", paramIndex))
+		arguments = append(arguments, q)
+		paramIndex++
+	}
+	if args.From > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("c.date >= $%v", paramIndex))
+		arguments = append(arguments, args.From)
+		paramIndex++
+	}
+	if args.To > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("c.date <= $%v", paramIndex))
+		arguments = append(arguments, args.To)
+		paramIndex++
+	}
+	if args.ArtistID > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("c.artist_id = $%v", paramIndex))
+		arguments = append(arguments, args.ArtistID)
+		paramIndex++
+	}
+	if args.VenueID > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("c.venue_id = $%v", paramIndex))
+		arguments = append(arguments, args.VenueID)
+		paramIndex++
+	}
+	if args.Available {
+		// Same rules as ListPurchasableTicketCategories.
+		whereClauses = append(whereClauses, fmt.Sprintf(`c."limit" > 0 AND EXISTS (
+			SELECT 1 FROM ticket_category tc
+			WHERE tc.concert_id = c.id
+				AND (tc.start_date = 0 OR tc.start_date <= $%[1]v)
+				AND (tc.end_date = 0 OR tc.end_date > $%[1]v)
+				AND tc.capacity - tc.sold - tc.held > 0
+		)`, paramIndex))
+		arguments = append(arguments, time.Now().Unix())
+		paramIndex++
+	}
+	if args.After != nil {
+		var key any = args.After.Date
+		if keyColumn == "c.name" {
+			key = args.After.Name
+		}
+		whereClauses = append(whereClauses, fmt.Sprintf("(%s, c.id) %s ($%v, $%v)", keyColumn, keyOp, paramIndex, paramIndex+1))
+		arguments = append(arguments, key, args.After.ID)
+		paramIndex += 2
+	}
+	if len(whereClauses) > 0 {
+		baseSql.WriteString(fmt.Sprintf("WHERE %s ", strings.Join(whereClauses, " AND ")))
+	}
+	// One extra row tells whether there is a next page.
+	baseSql.WriteString(fmt.Sprintf("ORDER BY %s LIMIT $%v;", orderBy, paramIndex))
+	arguments = append(arguments, args.Limit+1)
+	rows, err := cq.DB.Query(ctx, baseSql.String(), arguments...)
+	if err != nil {
+		return ConcertPage{}, err
+	}
+	defer rows.Close()
+	page := ConcertPage{Concerts: []Concert{}}
+	for rows.Next() {
+		t, err := scanConcert(rows)
+		if err != nil {
+			return ConcertPage{}, err
+		}
+		page.Concerts = append(page.Concerts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return ConcertPage{}, err
+	}
+	if len(page.Concerts) > args.Limit {
+		page.Concerts = page.Concerts[:args.Limit]
+		last := page.Concerts[len(page.Concerts)-1]
+		page.NextCursor = ConcertCursor{
+			Sort: args.Sort,
+			Date: last.Date,
+			Name: last.Name,
+			ID:   last.ID,
+		}.Encode()
+	}
+	return page, nil
+}
@@ -0,0 +1,130 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type CustomerID = int
+
+var ErrCustomerExists = errors.New("customer with this email already exists")
+
+type Customer struct {
+	ID    CustomerID `json:"id"`
+	Email string     `json:"email"`
+	Name  string     `json:"name,omitempty"`
+	// Empty for accounts created by an order or a transfer that were never signed up for.
+	PasswordHash string `json:"-"`
+	CreatedAt    int    `json:"created_at,omitempty"`
+	UpdatedAt    int    `json:"updated_at,omitempty"`
+}
+
+func (c Customer) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", c.ID),
+		slog.String("email", c.Email),
+	)
+}
+
+type CustomerQueryImpl struct {
+	DB DbTx
+}
+
+type CreateCustomerArgs struct {
+	Email        string
+	Name         string
+	PasswordHash string
+}
+
+// Returns ErrCustomerExists when the email already has an account, including one created
+// on the fly by an order or a transfer, so signing up cannot take over somebody else's tickets.
+func (cq *CustomerQueryImpl) CreateCustomer(ctx context.Context, args CreateCustomerArgs) (Customer, error) {
+	row := cq.DB.QueryRow(ctx, `
+		INSERT INTO customer (
+			email,
+			name,
+			password_hash,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, ''), $3, $4, $4
+		)
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id, email, COALESCE(name, ''), created_at, updated_at;
+	`, args.Email, args.Name, args.PasswordHash, time.Now().Unix())
+	var c Customer
+	err := row.Scan(
+		&c.ID,
+		&c.Email,
+		&c.Name,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return c, ErrCustomerExists
+	}
+	return c, err
+}
+
+func (cq *CustomerQueryImpl) GetCustomer(ctx context.Context, id CustomerID) (Customer, error) {
+	row := cq.DB.QueryRow(ctx, `
+		SELECT id, email, COALESCE(name, ''), created_at, updated_at
+		FROM customer
+		WHERE id = $1;
+	`, id)
+	var c Customer
+	err := row.Scan(
+		&c.ID,
+		&c.Email,
+		&c.Name,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	return c, err
+}
+
+func (cq *CustomerQueryImpl) GetCustomerByEmail(ctx context.Context, email string) (Customer, error) {
+	row := cq.DB.QueryRow(ctx, `
+		SELECT id, email, COALESCE(name, ''), COALESCE(password_hash, ''), created_at, updated_at
+		FROM customer
+		WHERE email = $1;
+	`, email)
+	var c Customer
+	err := row.Scan(
+		&c.ID,
+		&c.Email,
+		&c.Name,
+		&c.PasswordHash,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	return c, err
+}
+
+// Returns the customer with the given email, creating an account without a name if there is none,
+// so tickets bought or received for an email are owned by its account.
+func (cq *CustomerQueryImpl) EnsureCustomer(ctx context.Context, email string) (Customer, error) {
+	row := cq.DB.QueryRow(ctx, `
+		INSERT INTO customer (
+			email,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $2
+		)
+		ON CONFLICT (email) DO UPDATE SET email = EXCLUDED.email
+		RETURNING id, email, COALESCE(name, ''), created_at, updated_at;
+	`, email, time.Now().Unix())
+	var c Customer
+	err := row.Scan(
+		&c.ID,
+		&c.Email,
+		&c.Name,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	return c, err
+}
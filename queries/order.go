@@ -0,0 +1,83 @@
+package queries
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type OrderID = int
+
+type Order struct {
+	ID            OrderID  `json:"id"`
+	ConcertID     int      `json:"concert_id"`
+	CustomerEmail string   `json:"customer_email"`
+	Quantity      int      `json:"quantity"`
+	Tickets       []Ticket `json:"tickets,omitempty"`
+	CreatedAt     int      `json:"created_at,omitempty"`
+	UpdatedAt     int      `json:"updated_at,omitempty"`
+}
+
+func (o Order) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", o.ID),
+		slog.Int("concert_id", o.ConcertID),
+		slog.String("customer_email", o.CustomerEmail),
+		slog.Int("quantity", o.Quantity),
+	)
+}
+
+type OrderQueryImpl struct {
+	DB DbTx
+}
+
+type CreateOrderArgs struct {
+	ConcertID     int
+	CustomerEmail string
+	Quantity      int
+}
+
+func (oq *OrderQueryImpl) CreateOrder(ctx context.Context, args CreateOrderArgs) (Order, error) {
+	row := oq.DB.QueryRow(ctx, `
+		INSERT INTO orders (
+			concert_id,
+			customer_email,
+			quantity,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) RETURNING id, concert_id, customer_email, quantity, created_at;
+	`, args.ConcertID, args.CustomerEmail, args.Quantity, time.Now().Unix(), time.Now().Unix())
+	var o Order
+	err := row.Scan(
+		&o.ID,
+		&o.ConcertID,
+		&o.CustomerEmail,
+		&o.Quantity,
+		&o.CreatedAt,
+	)
+	return o, err
+}
+
+// Serializes purchases of the same customer for a concert until the transaction ends,
+// so the per-customer maximum cannot be bypassed with concurrent orders.
+func (oq *OrderQueryImpl) LockCustomer(ctx context.Context, concertID ConcertID, customerID CustomerID) error {
+	_, err := oq.DB.Exec(ctx, `
+		SELECT pg_advisory_xact_lock($1, $2);
+	`, concertID, customerID)
+	return err
+}
+
+// Number of live tickets a customer holds for a concert, however they were bought.
+// Cancelled and refunded tickets no longer count.
+func (oq *OrderQueryImpl) CountCustomerTickets(ctx context.Context, concertID ConcertID, customerID CustomerID) (int, error) {
+	row := oq.DB.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM ticket
+		WHERE concert_id = $1 AND customer_id = $2 AND status = ANY($3);
+	`, concertID, customerID, liveTicketStatuses)
+	var n int
+	err := row.Scan(&n)
+	return n, err
+}
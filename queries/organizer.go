@@ -0,0 +1,99 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type OrganizerID = int
+
+var ErrOrganizerExists = errors.New("organizer with this name already exists")
+
+type Organizer struct {
+	ID        OrganizerID `json:"id"`
+	Name      string      `json:"name"`
+	CreatedAt int         `json:"created_at,omitempty"`
+	UpdatedAt int         `json:"updated_at,omitempty"`
+}
+
+func (o Organizer) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", o.ID),
+		slog.String("name", o.Name),
+	)
+}
+
+type OrganizerQueryImpl struct {
+	DB DbTx
+}
+
+// Returns ErrOrganizerExists when the name is already taken.
+func (oq *OrganizerQueryImpl) CreateOrganizer(ctx context.Context, name string) (Organizer, error) {
+	row := oq.DB.QueryRow(ctx, `
+		INSERT INTO organizer (
+			name,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $2
+		)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, name, created_at, updated_at;
+	`, name, time.Now().Unix())
+	var o Organizer
+	err := row.Scan(
+		&o.ID,
+		&o.Name,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return o, ErrOrganizerExists
+	}
+	return o, err
+}
+
+func (oq *OrganizerQueryImpl) GetOrganizer(ctx context.Context, id OrganizerID) (Organizer, error) {
+	row := oq.DB.QueryRow(ctx, `
+		SELECT id, name, created_at, updated_at
+		FROM organizer
+		WHERE id = $1;
+	`, id)
+	var o Organizer
+	err := row.Scan(
+		&o.ID,
+		&o.Name,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	return o, err
+}
+
+func (oq *OrganizerQueryImpl) ListOrganizers(ctx context.Context) ([]Organizer, error) {
+	rows, err := oq.DB.Query(ctx, `
+		SELECT id, name, created_at, updated_at
+		FROM organizer
+		ORDER BY name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	organizers := []Organizer{}
+	for rows.Next() {
+		var o Organizer
+		if err := rows.Scan(
+			&o.ID,
+			&o.Name,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		organizers = append(organizers, o)
+	}
+	return organizers, rows.Err()
+}
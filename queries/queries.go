@@ -2,6 +2,7 @@ package queries
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -20,17 +21,103 @@ type Queries struct {
 		CreateTicket(ctx context.Context, args CreateTicketQueryArgs) (Ticket, error)
 		DeleteTicket(ctx context.Context, id TicketID) (Ticket, error)
 		GetTicket(ctx context.Context, id TicketID) (Ticket, error)
+		GetTicketBySerialNumber(ctx context.Context, serialNumber string) (Ticket, error)
+		ListConcertTickets(ctx context.Context, concertID ConcertID, statuses []TicketStatus) ([]Ticket, error)
+		ListCustomerTickets(ctx context.Context, customerID CustomerID) ([]Ticket, error)
+		TransitionTicket(ctx context.Context, id TicketID, to TicketStatus, actor string) (Ticket, error)
+		TransitionReservationTickets(ctx context.Context, reservationID ReservationID, to TicketStatus, actor string) ([]Ticket, error)
+		ListTicketHistory(ctx context.Context, id TicketID) ([]TicketStatusChange, error)
+		ReassignTicket(ctx context.Context, id TicketID, serialNumber string, holderName string, holderEmail string, customerID CustomerID) (Ticket, error)
 	}
 	Concert interface {
 		CreateConcert(ctx context.Context, args CreateConcertQueryArgs) (Concert, error)
-		DeleteConcert(ctx context.Context, id ConcertID) (Concert, error)
+		DeleteConcert(ctx context.Context, id ConcertID, organizerID OrganizerID) (Concert, error)
 		UpdateConcert(ctx context.Context, args UpdateConcertArgs) (Concert, error)
 		GetConcert(ctx context.Context, ID ConcertID) (Concert, error)
+		ListConcerts(ctx context.Context, organizerID OrganizerID) ([]Concert, error)
+		SearchConcerts(ctx context.Context, args SearchConcertsArgs) (ConcertPage, error)
+		DecrementConcertLimit(ctx context.Context, id ConcertID, quantity int) (Concert, error)
+		IncrementConcertLimit(ctx context.Context, id ConcertID, quantity int) (Concert, error)
 	}
 	TicketCategory interface {
 		UpdateTicketCategory(ctx context.Context, args UpdateTicketCategoryArgs) (TicketCategory, error)
-		DeleteTicketCategory(ctx context.Context, id TicketCategoryID) (TicketCategory, error)
+		DeleteTicketCategory(ctx context.Context, id TicketCategoryID, organizerID OrganizerID) (TicketCategory, error)
 		CreateTicketCategory(ctx context.Context, args CreateTicketCategoryArgs) (TicketCategory, error)
+		GetTicketCategory(ctx context.Context, id TicketCategoryID) (TicketCategory, error)
+		ListTicketCategories(ctx context.Context, concertID ConcertID, organizerID OrganizerID) ([]TicketCategory, error)
+		ListPurchasableTicketCategories(ctx context.Context, concertID ConcertID, now time.Time) ([]TicketCategory, error)
+		AdjustTicketCategoryInventory(ctx context.Context, id TicketCategoryID, soldDelta int, heldDelta int) (TicketCategory, error)
+	}
+	Reservation interface {
+		CreateReservation(ctx context.Context, args CreateReservationArgs) (Reservation, error)
+		GetReservation(ctx context.Context, id ReservationID) (Reservation, error)
+		CloseReservation(ctx context.Context, id ReservationID, customerID CustomerID, status ReservationStatus) (Reservation, error)
+		ExpireReservations(ctx context.Context) ([]Reservation, error)
+	}
+	Order interface {
+		CreateOrder(ctx context.Context, args CreateOrderArgs) (Order, error)
+		LockCustomer(ctx context.Context, concertID ConcertID, customerID CustomerID) error
+		CountCustomerTickets(ctx context.Context, concertID ConcertID, customerID CustomerID) (int, error)
+	}
+	Refund interface {
+		CreateRefund(ctx context.Context, args CreateRefundArgs) (Refund, error)
+	}
+	Customer interface {
+		CreateCustomer(ctx context.Context, args CreateCustomerArgs) (Customer, error)
+		GetCustomer(ctx context.Context, id CustomerID) (Customer, error)
+		GetCustomerByEmail(ctx context.Context, email string) (Customer, error)
+		EnsureCustomer(ctx context.Context, email string) (Customer, error)
+	}
+	Organizer interface {
+		CreateOrganizer(ctx context.Context, name string) (Organizer, error)
+		GetOrganizer(ctx context.Context, id OrganizerID) (Organizer, error)
+		ListOrganizers(ctx context.Context) ([]Organizer, error)
+	}
+	Artist interface {
+		CreateArtist(ctx context.Context, args CreateArtistArgs) (Artist, error)
+		GetArtist(ctx context.Context, id ArtistID) (Artist, error)
+		ListArtists(ctx context.Context) ([]Artist, error)
+		UpdateArtist(ctx context.Context, args UpdateArtistArgs) (Artist, error)
+		DeleteArtist(ctx context.Context, id ArtistID) (Artist, error)
+	}
+	Venue interface {
+		CreateVenue(ctx context.Context, args CreateVenueArgs) (Venue, error)
+		GetVenue(ctx context.Context, id VenueID) (Venue, error)
+		ListVenues(ctx context.Context) ([]Venue, error)
+		UpdateVenue(ctx context.Context, args UpdateVenueArgs) (Venue, error)
+		DeleteVenue(ctx context.Context, id VenueID) (Venue, error)
+	}
+	VenueSection interface {
+		CreateVenueSection(ctx context.Context, args CreateVenueSectionArgs) (VenueSection, error)
+		GetVenueSection(ctx context.Context, id VenueSectionID) (VenueSection, error)
+		ListVenueSections(ctx context.Context, venueID VenueID) ([]VenueSection, error)
+		UpdateVenueSection(ctx context.Context, args UpdateVenueSectionArgs) (VenueSection, error)
+		DeleteVenueSection(ctx context.Context, id VenueSectionID) (VenueSection, error)
+	}
+	Seat interface {
+		CreateSeats(ctx context.Context, args CreateSeatsArgs) ([]Seat, error)
+		GetSeat(ctx context.Context, id SeatID) (Seat, error)
+		ListSeats(ctx context.Context, sectionID VenueSectionID) ([]Seat, error)
+		CountSeats(ctx context.Context, sectionIDs []VenueSectionID) (int, error)
+		DeleteSeat(ctx context.Context, id SeatID) (Seat, error)
+		ListConcertSeats(ctx context.Context, concertID ConcertID, sectionID VenueSectionID) ([]ConcertSeat, error)
+		CheckSeat(ctx context.Context, concertID ConcertID, sectionIDs []VenueSectionID, id SeatID) (Seat, error)
+	}
+	Staff interface {
+		CreateStaff(ctx context.Context, args CreateStaffArgs) (Staff, error)
+		GetStaffByEmail(ctx context.Context, email string) (Staff, error)
+	}
+	APIKey interface {
+		CreateAPIKey(ctx context.Context, args CreateAPIKeyArgs) (APIKey, error)
+		GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error)
+		RevokeAPIKey(ctx context.Context, id APIKeyID) (APIKey, error)
+	}
+	TicketTransfer interface {
+		CreateTicketTransfer(ctx context.Context, args CreateTicketTransferArgs) (TicketTransfer, error)
+		GetTicketTransfer(ctx context.Context, id TicketTransferID) (TicketTransfer, error)
+		AcceptTicketTransfer(ctx context.Context, args AcceptTicketTransferArgs) (TicketTransfer, error)
+		CancelTicketTransfer(ctx context.Context, id TicketTransferID, fromEmail string) (TicketTransfer, error)
+		ListTicketTransfers(ctx context.Context, ticketID TicketID) ([]TicketTransfer, error)
 	}
 }
 
@@ -40,6 +127,18 @@ func NewQueries(db DbTx) Queries {
 		Concert:        &ConcertQueryImpl{DB: db},
 		TicketCategory: &TicketCategoryQueryImpl{DB: db},
 		Ticket:         &TicketQueryImpl{DB: db},
+		Reservation:    &ReservationQueryImpl{DB: db},
+		Order:          &OrderQueryImpl{DB: db},
+		Refund:         &RefundQueryImpl{DB: db},
+		TicketTransfer: &TicketTransferQueryImpl{DB: db},
+		Customer:       &CustomerQueryImpl{DB: db},
+		Staff:          &StaffQueryImpl{DB: db},
+		Organizer:      &OrganizerQueryImpl{DB: db},
+		APIKey:         &APIKeyQueryImpl{DB: db},
+		Artist:         &ArtistQueryImpl{DB: db},
+		Venue:          &VenueQueryImpl{DB: db},
+		VenueSection:   &VenueSectionQueryImpl{DB: db},
+		Seat:           &SeatQueryImpl{DB: db},
 	}
 }
 
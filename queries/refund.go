@@ -0,0 +1,59 @@
+package queries
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type RefundID = int
+
+type Refund struct {
+	ID         RefundID `json:"id"`
+	TicketID   int      `json:"ticket_id"`
+	Amount     float64  `json:"amount"`
+	Percentage float64  `json:"percentage"`
+	CreatedAt  int      `json:"created_at,omitempty"`
+	UpdatedAt  int      `json:"updated_at,omitempty"`
+}
+
+func (r Refund) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", r.ID),
+		slog.Int("ticket_id", r.TicketID),
+		slog.Float64("amount", r.Amount),
+	)
+}
+
+type RefundQueryImpl struct {
+	DB DbTx
+}
+
+type CreateRefundArgs struct {
+	TicketID   int
+	Amount     float64
+	Percentage float64
+}
+
+func (rq *RefundQueryImpl) CreateRefund(ctx context.Context, args CreateRefundArgs) (Refund, error) {
+	row := rq.DB.QueryRow(ctx, `
+		INSERT INTO refund (
+			ticket_id,
+			amount,
+			percentage,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) RETURNING id, ticket_id, amount, percentage, created_at;
+	`, args.TicketID, args.Amount, args.Percentage, time.Now().Unix(), time.Now().Unix())
+	var r Refund
+	err := row.Scan(
+		&r.ID,
+		&r.TicketID,
+		&r.Amount,
+		&r.Percentage,
+		&r.CreatedAt,
+	)
+	return r, err
+}
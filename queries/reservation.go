@@ -0,0 +1,185 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type ReservationID = int
+
+type ReservationStatus = string
+
+const (
+	ReservationHeld      ReservationStatus = "held"
+	ReservationConfirmed ReservationStatus = "confirmed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+var (
+	ErrReservationNotHeld = errors.New("reservation is no longer held")
+	ErrReservationExpired = errors.New("reservation expired")
+)
+
+type Reservation struct {
+	ID               ReservationID     `json:"id"`
+	ConcertID        int               `json:"concert_id"`
+	TicketCategoryID int               `json:"ticket_category"`
+	CustomerID       CustomerID        `json:"customer_id,omitempty"`
+	Quantity         int               `json:"quantity"`
+	Status           ReservationStatus `json:"status"`
+	ExpiresAt        int               `json:"expires_at"`
+	Tickets          []Ticket          `json:"tickets,omitempty"`
+	CreatedAt        int               `json:"created_at,omitempty"`
+	UpdatedAt        int               `json:"updated_at,omitempty"`
+}
+
+func (r Reservation) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", r.ID),
+		slog.Int("concert_id", r.ConcertID),
+		slog.Int("quantity", r.Quantity),
+		slog.String("status", r.Status),
+	)
+}
+
+type ReservationQueryImpl struct {
+	DB DbTx
+}
+
+type CreateReservationArgs struct {
+	ConcertID        int
+	TicketCategoryID int
+	CustomerID       CustomerID
+	Quantity         int
+	ExpiresAt        int
+}
+
+func (rq *ReservationQueryImpl) CreateReservation(ctx context.Context, args CreateReservationArgs) (Reservation, error) {
+	row := rq.DB.QueryRow(ctx, `
+		INSERT INTO reservation (
+			concert_id,
+			ticket_category_id,
+			quantity,
+			status,
+			expires_at,
+			created_at,
+			updated_at,
+			customer_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0)
+		) RETURNING id, concert_id, ticket_category_id, COALESCE(customer_id, 0), quantity, status, expires_at;
+	`, args.ConcertID, args.TicketCategoryID, args.Quantity, ReservationHeld, args.ExpiresAt, time.Now().Unix(), time.Now().Unix(), args.CustomerID)
+	var r Reservation
+	err := row.Scan(
+		&r.ID,
+		&r.ConcertID,
+		&r.TicketCategoryID,
+		&r.CustomerID,
+		&r.Quantity,
+		&r.Status,
+		&r.ExpiresAt,
+	)
+	return r, err
+}
+
+func (rq *ReservationQueryImpl) GetReservation(ctx context.Context, id ReservationID) (Reservation, error) {
+	row := rq.DB.QueryRow(ctx, `
+		SELECT
+			id,
+			concert_id,
+			ticket_category_id,
+			COALESCE(customer_id, 0),
+			quantity,
+			status,
+			expires_at
+		FROM reservation
+		WHERE id = $1;
+	`, id)
+	var r Reservation
+	err := row.Scan(
+		&r.ID,
+		&r.ConcertID,
+		&r.TicketCategoryID,
+		&r.CustomerID,
+		&r.Quantity,
+		&r.Status,
+		&r.ExpiresAt,
+	)
+	return r, err
+}
+
+// Moves a held, unexpired reservation of the customer to the given status.
+// Fails with ErrReservationNotHeld or ErrReservationExpired otherwise,
+// and with sql.ErrNoRows when the reservation belongs to somebody else.
+func (rq *ReservationQueryImpl) CloseReservation(ctx context.Context, id ReservationID, customerID CustomerID, status ReservationStatus) (Reservation, error) {
+	now := time.Now().Unix()
+	row := rq.DB.QueryRow(ctx, `
+		UPDATE reservation
+		SET status = $2,
+			updated_at = $3
+		WHERE id = $1 AND customer_id = $5 AND status = $4 AND expires_at > $3
+		RETURNING id, concert_id, ticket_category_id, COALESCE(customer_id, 0), quantity, status, expires_at;
+	`, id, status, now, ReservationHeld, customerID)
+	var r Reservation
+	err := row.Scan(
+		&r.ID,
+		&r.ConcertID,
+		&r.TicketCategoryID,
+		&r.CustomerID,
+		&r.Quantity,
+		&r.Status,
+		&r.ExpiresAt,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		current, err := rq.GetReservation(ctx, id)
+		if err != nil {
+			return r, err
+		}
+		if current.CustomerID != customerID {
+			return r, sql.ErrNoRows
+		}
+		if current.Status == ReservationHeld {
+			return current, ErrReservationExpired
+		}
+		return current, ErrReservationNotHeld
+	}
+	return r, err
+}
+
+// Marks every held reservation past its expiry as expired and returns them,
+// so the caller can give their inventory back.
+func (rq *ReservationQueryImpl) ExpireReservations(ctx context.Context) ([]Reservation, error) {
+	now := time.Now().Unix()
+	rows, err := rq.DB.Query(ctx, `
+		UPDATE reservation
+		SET status = $1,
+			updated_at = $2
+		WHERE status = $3 AND expires_at <= $2
+		RETURNING id, concert_id, ticket_category_id, COALESCE(customer_id, 0), quantity, status, expires_at;
+	`, ReservationExpired, now, ReservationHeld)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var reservations []Reservation
+	for rows.Next() {
+		var r Reservation
+		if err := rows.Scan(
+			&r.ID,
+			&r.ConcertID,
+			&r.TicketCategoryID,
+			&r.CustomerID,
+			&r.Quantity,
+			&r.Status,
+			&r.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, rows.Err()
+}
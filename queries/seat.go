@@ -0,0 +1,276 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+type SeatID = int
+
+type SeatStatus = string
+
+const (
+	SeatAvailable SeatStatus = "available"
+	// Held by a reservation that is not confirmed yet.
+	SeatHeld SeatStatus = "held"
+	SeatSold SeatStatus = "sold"
+)
+
+// Tickets in these statuses keep their seat, matching the ticket_seat_unique index.
+var seatHoldingStatuses = liveTicketStatuses
+
+var (
+	ErrSeatTaken            = errors.New("seat already has a ticket for the concert")
+	ErrSeatInUse            = errors.New("seat has tickets")
+	ErrSeatsExceedSection   = errors.New("seats exceed the venue section capacity")
+	ErrSeatNotInSection     = errors.New("seat does not belong to a ticket category section")
+	ErrSeatRequired         = errors.New("ticket category is reserved seating and needs a seat")
+	ErrSeatGeneralAdmission = errors.New("ticket category is general admission and has no seats")
+)
+
+// A numbered seat of a venue section, RowRank counts rows from the stage with 1 being the front row.
+type Seat struct {
+	ID        SeatID         `json:"id"`
+	SectionID VenueSectionID `json:"section_id"`
+	Row       string         `json:"row"`
+	RowRank   int            `json:"row_rank"`
+	Number    int            `json:"number"`
+	CreatedAt int            `json:"created_at,omitempty"`
+	UpdatedAt int            `json:"updated_at,omitempty"`
+}
+
+func (s Seat) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", s.ID),
+		slog.Int("section_id", s.SectionID),
+		slog.String("row", s.Row),
+		slog.Int("row_rank", s.RowRank),
+		slog.Int("number", s.Number),
+	)
+}
+
+// A seat of the concert venue and whether it can still be bought.
+type ConcertSeat struct {
+	Seat
+	Section         string     `json:"section"`
+	SectionPriority int        `json:"section_priority"`
+	Status          SeatStatus `json:"status"`
+}
+
+type SeatQueryImpl struct {
+	DB DbTx
+}
+
+const seatColumns = `id, section_id, "row", row_rank, number, created_at, updated_at`
+
+func scanSeat(row interface{ Scan(dest ...any) error }) (Seat, error) {
+	var s Seat
+	err := row.Scan(
+		&s.ID,
+		&s.SectionID,
+		&s.Row,
+		&s.RowRank,
+		&s.Number,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	return s, err
+}
+
+type CreateSeatsArgs struct {
+	SectionID VenueSectionID
+	Row       string
+	// Zero keeps the rank of an existing row or puts a new row behind the others.
+	RowRank int
+	// Seat numbers From through To, both included.
+	From int
+	To   int
+}
+
+// Adds a row of seats to a section, seats that already exist are left alone.
+// Run it in a transaction, it fails with ErrSeatsExceedSection after inserting
+// when the section ends up with more seats than its capacity.
+func (sq *SeatQueryImpl) CreateSeats(ctx context.Context, args CreateSeatsArgs) ([]Seat, error) {
+	var capacity int
+	err := sq.DB.QueryRow(ctx, `
+		SELECT capacity FROM venue_section WHERE id = $1 FOR UPDATE;
+	`, args.SectionID).Scan(&capacity)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sq.DB.Query(ctx, `
+		INSERT INTO seat (section_id, "row", row_rank, number, created_at, updated_at)
+		SELECT $1, $2, COALESCE(
+			NULLIF($6, 0),
+			(SELECT MIN(row_rank) FROM seat WHERE section_id = $1 AND "row" = $2),
+			(SELECT COALESCE(MAX(row_rank), 0) + 1 FROM seat WHERE section_id = $1)
+		), n, $5, $5
+		FROM generate_series($3::int, $4::int) AS n
+		ON CONFLICT (section_id, "row", number) DO NOTHING
+		RETURNING `+seatColumns+`;
+	`, args.SectionID, args.Row, args.From, args.To, time.Now().Unix(), args.RowRank)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seats := []Seat{}
+	for rows.Next() {
+		s, err := scanSeat(rows)
+		if err != nil {
+			return nil, err
+		}
+		seats = append(seats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var count int
+	err = sq.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM seat WHERE section_id = $1;
+	`, args.SectionID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > capacity {
+		return nil, ErrSeatsExceedSection
+	}
+	return seats, nil
+}
+
+func (sq *SeatQueryImpl) GetSeat(ctx context.Context, id SeatID) (Seat, error) {
+	row := sq.DB.QueryRow(ctx, `
+		SELECT `+seatColumns+`
+		FROM seat
+		WHERE id = $1;
+	`, id)
+	return scanSeat(row)
+}
+
+func (sq *SeatQueryImpl) ListSeats(ctx context.Context, sectionID VenueSectionID) ([]Seat, error) {
+	rows, err := sq.DB.Query(ctx, `
+		SELECT `+seatColumns+`
+		FROM seat
+		WHERE section_id = $1
+		ORDER BY row_rank, "row", number;
+	`, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seats := []Seat{}
+	for rows.Next() {
+		s, err := scanSeat(rows)
+		if err != nil {
+			return nil, err
+		}
+		seats = append(seats, s)
+	}
+	return seats, rows.Err()
+}
+
+// Seats of every section given.
+func (sq *SeatQueryImpl) CountSeats(ctx context.Context, sectionIDs []VenueSectionID) (int, error) {
+	var count int
+	err := sq.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM seat WHERE section_id = ANY($1);
+	`, sectionIDs).Scan(&count)
+	return count, err
+}
+
+// Returns ErrSeatInUse once any ticket was issued for the seat.
+func (sq *SeatQueryImpl) DeleteSeat(ctx context.Context, id SeatID) (Seat, error) {
+	row := sq.DB.QueryRow(ctx, `
+		DELETE FROM seat
+		WHERE id = $1
+			AND NOT EXISTS (SELECT 1 FROM ticket WHERE seat_id = $1)
+		RETURNING `+seatColumns+`;
+	`, id)
+	s, err := scanSeat(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := sq.GetSeat(ctx, id); err != nil {
+			return s, err
+		}
+		return s, ErrSeatInUse
+	}
+	return s, err
+}
+
+// Every seat of the concert venue with its status, limited to one section when sectionID is not zero.
+func (sq *SeatQueryImpl) ListConcertSeats(ctx context.Context, concertID ConcertID, sectionID VenueSectionID) ([]ConcertSeat, error) {
+	rows, err := sq.DB.Query(ctx, `
+		SELECT
+			s.id,
+			s.section_id,
+			s."row",
+			s.row_rank,
+			s.number,
+			s.created_at,
+			s.updated_at,
+			vs.name,
+			vs.priority,
+			CASE
+				WHEN t.status IS NULL THEN $4
+				WHEN t.status = $5 THEN $6
+				ELSE $7
+			END
+		FROM seat s
+		JOIN venue_section vs ON vs.id = s.section_id
+		JOIN concert c ON c.venue_id = vs.venue_id
+		LEFT JOIN ticket t ON t.seat_id = s.id AND t.concert_id = c.id AND t.status = ANY($3)
+		WHERE c.id = $1 AND ($2 = 0 OR s.section_id = $2)
+		ORDER BY vs.name, s.row_rank, s."row", s.number;
+	`, concertID, sectionID, seatHoldingStatuses, SeatAvailable, TicketReserved, SeatHeld, SeatSold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seats := []ConcertSeat{}
+	for rows.Next() {
+		var s ConcertSeat
+		if err := rows.Scan(
+			&s.ID,
+			&s.SectionID,
+			&s.Row,
+			&s.RowRank,
+			&s.Number,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.Section,
+			&s.SectionPriority,
+			&s.Status,
+		); err != nil {
+			return nil, err
+		}
+		seats = append(seats, s)
+	}
+	return seats, rows.Err()
+}
+
+// Fails with ErrSeatNotInSection unless the seat lies in one of the sections,
+// and with ErrSeatTaken when it already has a live ticket for the concert.
+func (sq *SeatQueryImpl) CheckSeat(ctx context.Context, concertID ConcertID, sectionIDs []VenueSectionID, id SeatID) (Seat, error) {
+	seat, err := sq.GetSeat(ctx, id)
+	if err != nil {
+		return seat, err
+	}
+	if !slices.Contains(sectionIDs, seat.SectionID) {
+		return seat, ErrSeatNotInSection
+	}
+	var taken bool
+	err = sq.DB.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM ticket WHERE concert_id = $1 AND seat_id = $2 AND status = ANY($3)
+		);
+	`, concertID, id, seatHoldingStatuses).Scan(&taken)
+	if err != nil {
+		return seat, err
+	}
+	if taken {
+		return seat, ErrSeatTaken
+	}
+	return seat, nil
+}
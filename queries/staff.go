@@ -0,0 +1,98 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type StaffID = int
+
+var ErrStaffExists = errors.New("staff member with this email already exists")
+
+type Staff struct {
+	ID           StaffID `json:"id"`
+	Email        string  `json:"email"`
+	Name         string  `json:"name,omitempty"`
+	Role         string  `json:"role"`
+	OrganizerID  int     `json:"organizer_id,omitempty"`
+	PasswordHash string  `json:"-"`
+	CreatedAt    int     `json:"created_at,omitempty"`
+	UpdatedAt    int     `json:"updated_at,omitempty"`
+}
+
+func (s Staff) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", s.ID),
+		slog.String("email", s.Email),
+		slog.String("role", s.Role),
+	)
+}
+
+type StaffQueryImpl struct {
+	DB DbTx
+}
+
+type CreateStaffArgs struct {
+	Email        string
+	Name         string
+	Role         string
+	OrganizerID  OrganizerID
+	PasswordHash string
+}
+
+// Returns ErrStaffExists when the email is already taken.
+func (sq *StaffQueryImpl) CreateStaff(ctx context.Context, args CreateStaffArgs) (Staff, error) {
+	row := sq.DB.QueryRow(ctx, `
+		INSERT INTO staff (
+			email,
+			name,
+			role,
+			organizer_id,
+			password_hash,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, ''), $3, NULLIF($6, 0), $4, $5, $5
+		)
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id, email, COALESCE(name, ''), role, COALESCE(organizer_id, 0), password_hash, created_at, updated_at;
+	`, args.Email, args.Name, args.Role, args.PasswordHash, time.Now().Unix(), args.OrganizerID)
+	var s Staff
+	err := row.Scan(
+		&s.ID,
+		&s.Email,
+		&s.Name,
+		&s.Role,
+		&s.OrganizerID,
+		&s.PasswordHash,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return s, ErrStaffExists
+	}
+	return s, err
+}
+
+func (sq *StaffQueryImpl) GetStaffByEmail(ctx context.Context, email string) (Staff, error) {
+	row := sq.DB.QueryRow(ctx, `
+		SELECT id, email, COALESCE(name, ''), role, COALESCE(organizer_id, 0), password_hash, created_at, updated_at
+		FROM staff
+		WHERE email = $1;
+	`, email)
+	var s Staff
+	err := row.Scan(
+		&s.ID,
+		&s.Email,
+		&s.Name,
+		&s.Role,
+		&s.OrganizerID,
+		&s.PasswordHash,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	return s, err
+}
@@ -2,17 +2,61 @@ package queries
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type TicketID = int
 
+type TicketStatus = string
+
+const (
+	TicketReserved    TicketStatus = "reserved"
+	TicketIssued      TicketStatus = "issued"
+	TicketCheckedIn   TicketStatus = "checked_in"
+	TicketCancelled   TicketStatus = "cancelled"
+	TicketRefunded    TicketStatus = "refunded"
+	TicketTransferred TicketStatus = "transferred"
+)
+
+// Tickets that still get in or may still be confirmed.
+var liveTicketStatuses = []TicketStatus{TicketReserved, TicketIssued, TicketTransferred, TicketCheckedIn}
+
+// Allowed moves of the ticket lifecycle, keyed by the target status.
+var ticketTransitions = map[TicketStatus][]TicketStatus{
+	TicketIssued:      {TicketReserved},
+	TicketCheckedIn:   {TicketIssued, TicketTransferred},
+	TicketCancelled:   {TicketReserved, TicketIssued, TicketTransferred},
+	TicketRefunded:    {TicketCancelled},
+	TicketTransferred: {TicketIssued, TicketTransferred},
+}
+
+var ErrInvalidTicketTransition = errors.New("invalid ticket status transition")
+
+func CanTransitionTicket(from TicketStatus, to TicketStatus) bool {
+	return slices.Contains(ticketTransitions[to], from)
+}
+
 type Ticket struct {
 	ID               TicketID `json:"id"`
 	SerialNumber     string   `json:"serial_number"`
 	ConcertID        int      `json:"concert_id"`
 	TicketCategoryID int      `json:"ticket_category"`
+	OrderID          int      `json:"order_id,omitempty"`
+	ReservationID    int      `json:"reservation_id,omitempty"`
+	Status           string   `json:"status"`
+	CancelledAt      int      `json:"cancelled_at,omitempty"`
+	CheckedInAt      int      `json:"checked_in_at,omitempty"`
+	HolderName       string   `json:"holder_name,omitempty"`
+	HolderEmail      string   `json:"holder_email,omitempty"`
+	CustomerID       int      `json:"customer_id,omitempty"`
+	SeatID           int      `json:"seat_id,omitempty"`
+	Token            string   `json:"token,omitempty"`
 	CreatedAt        int      `json:"created_at,omitempty"`
 	UpdatedAt        int      `json:"updated_at,omitempty"`
 }
@@ -21,9 +65,19 @@ func (t Ticket) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.Int("ticket_id", t.ID),
 		slog.String("serial_number", t.SerialNumber),
+		slog.String("status", t.Status),
 	)
 }
 
+type TicketStatusChange struct {
+	ID         int          `json:"id"`
+	TicketID   TicketID     `json:"ticket_id"`
+	FromStatus TicketStatus `json:"from_status,omitempty"`
+	ToStatus   TicketStatus `json:"to_status"`
+	Actor      string       `json:"actor"`
+	CreatedAt  int          `json:"created_at"`
+}
+
 type TicketQueryImpl struct {
 	DB DbTx
 }
@@ -31,50 +85,257 @@ type TicketQueryImpl struct {
 type CreateTicketQueryArgs struct {
 	ConcertID        int
 	TicketCategoryID int
+	// Zero when the ticket was not bought through an order.
+	OrderID int
+	// Zero when the ticket was not held by a reservation.
+	ReservationID int
+	// Defaults to issued.
+	Status TicketStatus
+	// Who caused the change, recorded in the status history.
+	Actor       string
+	HolderName  string
+	HolderEmail string
+	// Zero when the ticket is not owned by a customer account.
+	CustomerID int
+	// Zero for general admission.
+	SeatID SeatID
 }
 
+// Fails with ErrSeatTaken when the seat already has a live ticket for the concert.
 func (tq *TicketQueryImpl) CreateTicket(ctx context.Context, args CreateTicketQueryArgs) (Ticket, error) {
+	if args.Status == "" {
+		args.Status = TicketIssued
+	}
 	row := tq.DB.QueryRow(ctx, `
-		INSERT INTO ticket (
-			concert_id,
-			ticket_category_id,
-			created_at,
-			updated_at
-		) VALUES (
-			$1,
-			$2,
-			$3,
-			$4
-		) RETURNING id, serial_number, concert_id, ticket_category_id;
-	`, args.ConcertID, args.TicketCategoryID, time.Now().Unix(), time.Now().Unix())
+		WITH t AS (
+			INSERT INTO ticket (
+				concert_id,
+				ticket_category_id,
+				order_id,
+				reservation_id,
+				status,
+				holder_name,
+				holder_email,
+				customer_id,
+				seat_id,
+				created_at,
+				updated_at
+			) VALUES (
+				$1,
+				$2,
+				NULLIF($3, 0),
+				NULLIF($4, 0),
+				$5,
+				NULLIF($8, ''),
+				NULLIF($9, ''),
+				NULLIF($10, 0),
+				NULLIF($11, 0),
+				$7,
+				$7
+			) RETURNING id, serial_number, concert_id, ticket_category_id, order_id, reservation_id, status, holder_name, holder_email, customer_id, seat_id
+		), h AS (
+			INSERT INTO ticket_status_history (ticket_id, from_status, to_status, actor, created_at)
+			SELECT id, NULL, status, $6, $7 FROM t
+		)
+		SELECT id, serial_number, concert_id, ticket_category_id, COALESCE(order_id, 0), COALESCE(reservation_id, 0), status,
+			COALESCE(holder_name, ''), COALESCE(holder_email, ''), COALESCE(customer_id, 0), COALESCE(seat_id, 0)
+		FROM t;
+	`, args.ConcertID, args.TicketCategoryID, args.OrderID, args.ReservationID, args.Status, args.Actor, time.Now().Unix(), args.HolderName, args.HolderEmail, args.CustomerID, args.SeatID)
 	var t Ticket
 	err := row.Scan(
 		&t.ID,
 		&t.SerialNumber,
 		&t.ConcertID,
 		&t.TicketCategoryID,
+		&t.OrderID,
+		&t.ReservationID,
+		&t.Status,
+		&t.HolderName,
+		&t.HolderEmail,
+		&t.CustomerID,
+		&t.SeatID,
 	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "ticket_seat_unique" {
+		return t, ErrSeatTaken
+	}
 	return t, err
 }
 
 func (tq *TicketQueryImpl) GetTicket(ctx context.Context, id TicketID) (Ticket, error) {
 	row := tq.DB.QueryRow(ctx, `
 		SELECT
+			id,
 			serial_number,
 			concert_id,
-			ticket_category_id
+			ticket_category_id,
+			COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0),
+			status,
+			COALESCE(cancelled_at, 0),
+			COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''),
+			COALESCE(holder_email, ''),
+			COALESCE(customer_id, 0),
+			COALESCE(seat_id, 0)
 		FROM ticket
-		WHERE id = $1;	
+		WHERE id = $1;
 	`, id)
 	var t Ticket
 	err := row.Scan(
+		&t.ID,
+		&t.SerialNumber,
+		&t.ConcertID,
+		&t.TicketCategoryID,
+		&t.OrderID,
+		&t.ReservationID,
+		&t.Status,
+		&t.CancelledAt,
+		&t.CheckedInAt,
+		&t.HolderName,
+		&t.HolderEmail,
+		&t.CustomerID,
+		&t.SeatID,
+	)
+	return t, err
+}
+
+func (tq *TicketQueryImpl) GetTicketBySerialNumber(ctx context.Context, serialNumber string) (Ticket, error) {
+	row := tq.DB.QueryRow(ctx, `
+		SELECT
+			id,
+			serial_number,
+			concert_id,
+			ticket_category_id,
+			COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0),
+			status,
+			COALESCE(cancelled_at, 0),
+			COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''),
+			COALESCE(holder_email, ''),
+			COALESCE(customer_id, 0),
+			COALESCE(seat_id, 0)
+		FROM ticket
+		WHERE serial_number = $1;
+	`, serialNumber)
+	var t Ticket
+	err := row.Scan(
+		&t.ID,
 		&t.SerialNumber,
 		&t.ConcertID,
 		&t.TicketCategoryID,
+		&t.OrderID,
+		&t.ReservationID,
+		&t.Status,
+		&t.CancelledAt,
+		&t.CheckedInAt,
+		&t.HolderName,
+		&t.HolderEmail,
+		&t.CustomerID,
+		&t.SeatID,
 	)
 	return t, err
 }
 
+func (tq *TicketQueryImpl) ListConcertTickets(ctx context.Context, concertID ConcertID, statuses []TicketStatus) ([]Ticket, error) {
+	rows, err := tq.DB.Query(ctx, `
+		SELECT
+			id,
+			serial_number,
+			concert_id,
+			ticket_category_id,
+			COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0),
+			status,
+			COALESCE(cancelled_at, 0),
+			COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''),
+			COALESCE(holder_email, ''),
+			COALESCE(customer_id, 0),
+			COALESCE(seat_id, 0)
+		FROM ticket
+		WHERE concert_id = $1 AND status = ANY($2)
+		ORDER BY id;
+	`, concertID, statuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tickets := []Ticket{}
+	for rows.Next() {
+		var t Ticket
+		if err := rows.Scan(
+			&t.ID,
+			&t.SerialNumber,
+			&t.ConcertID,
+			&t.TicketCategoryID,
+			&t.OrderID,
+			&t.ReservationID,
+			&t.Status,
+			&t.CancelledAt,
+			&t.CheckedInAt,
+			&t.HolderName,
+			&t.HolderEmail,
+			&t.CustomerID,
+			&t.SeatID,
+		); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+func (tq *TicketQueryImpl) ListCustomerTickets(ctx context.Context, customerID CustomerID) ([]Ticket, error) {
+	rows, err := tq.DB.Query(ctx, `
+		SELECT
+			id,
+			serial_number,
+			concert_id,
+			ticket_category_id,
+			COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0),
+			status,
+			COALESCE(cancelled_at, 0),
+			COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''),
+			COALESCE(holder_email, ''),
+			COALESCE(customer_id, 0),
+			COALESCE(seat_id, 0)
+		FROM ticket
+		WHERE customer_id = $1
+		ORDER BY id DESC;
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tickets := []Ticket{}
+	for rows.Next() {
+		var t Ticket
+		if err := rows.Scan(
+			&t.ID,
+			&t.SerialNumber,
+			&t.ConcertID,
+			&t.TicketCategoryID,
+			&t.OrderID,
+			&t.ReservationID,
+			&t.Status,
+			&t.CancelledAt,
+			&t.CheckedInAt,
+			&t.HolderName,
+			&t.HolderEmail,
+			&t.CustomerID,
+			&t.SeatID,
+		); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
 func (tq *TicketQueryImpl) DeleteTicket(ctx context.Context, id TicketID) (Ticket, error) {
 	row := tq.DB.QueryRow(ctx, `
 		DELETE FROM ticket
@@ -88,3 +349,161 @@ func (tq *TicketQueryImpl) DeleteTicket(ctx context.Context, id TicketID) (Ticke
 	)
 	return t, err
 }
+
+// Moves the ticket to the given status and records the change in its history.
+// Fails with ErrInvalidTicketTransition when the lifecycle does not allow the move.
+func (tq *TicketQueryImpl) TransitionTicket(ctx context.Context, id TicketID, to TicketStatus, actor string) (Ticket, error) {
+	tickets, err := tq.transitionTickets(ctx, "id", id, to, actor)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if len(tickets) == 0 {
+		current, err := tq.GetTicket(ctx, id)
+		if err != nil {
+			return current, err
+		}
+		return current, fmt.Errorf("%w: %s -> %s", ErrInvalidTicketTransition, current.Status, to)
+	}
+	return tickets[0], nil
+}
+
+// Moves every ticket held by a reservation that is allowed to reach the given status.
+func (tq *TicketQueryImpl) TransitionReservationTickets(ctx context.Context, reservationID ReservationID, to TicketStatus, actor string) ([]Ticket, error) {
+	return tq.transitionTickets(ctx, "reservation_id", reservationID, to, actor)
+}
+
+// column is never user input.
+func (tq *TicketQueryImpl) transitionTickets(ctx context.Context, column string, value int, to TicketStatus, actor string) ([]Ticket, error) {
+	rows, err := tq.DB.Query(ctx, fmt.Sprintf(`
+		WITH prev AS (
+			SELECT id, status
+			FROM ticket
+			WHERE %s = $1
+			FOR UPDATE
+		), t AS (
+			UPDATE ticket
+			SET status = $2,
+				cancelled_at = CASE WHEN $2 = '%s' THEN $4 ELSE cancelled_at END,
+				checked_in_at = CASE WHEN $2 = '%s' THEN $4 ELSE checked_in_at END,
+				updated_at = $4
+			FROM prev
+			WHERE ticket.id = prev.id AND prev.status = ANY($3)
+			RETURNING ticket.id, ticket.serial_number, ticket.concert_id, ticket.ticket_category_id,
+				ticket.order_id, ticket.reservation_id, ticket.status, ticket.cancelled_at, ticket.checked_in_at,
+				ticket.holder_name, ticket.holder_email, ticket.customer_id, ticket.seat_id, prev.status AS from_status
+		), h AS (
+			INSERT INTO ticket_status_history (ticket_id, from_status, to_status, actor, created_at)
+			SELECT id, from_status, status, $5, $4 FROM t
+		)
+		SELECT id, serial_number, concert_id, ticket_category_id, COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0), status, COALESCE(cancelled_at, 0), COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''), COALESCE(holder_email, ''), COALESCE(customer_id, 0), COALESCE(seat_id, 0)
+		FROM t
+		ORDER BY id;
+	`, column, TicketCancelled, TicketCheckedIn), value, to, ticketTransitions[to], time.Now().Unix(), actor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tickets []Ticket
+	for rows.Next() {
+		var t Ticket
+		if err := rows.Scan(
+			&t.ID,
+			&t.SerialNumber,
+			&t.ConcertID,
+			&t.TicketCategoryID,
+			&t.OrderID,
+			&t.ReservationID,
+			&t.Status,
+			&t.CancelledAt,
+			&t.CheckedInAt,
+			&t.HolderName,
+			&t.HolderEmail,
+			&t.CustomerID,
+			&t.SeatID,
+		); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+// Hands the ticket to a new holder under a new serial number,
+// so codes printed for the previous holder stop matching the ticket.
+func (tq *TicketQueryImpl) ReassignTicket(ctx context.Context, id TicketID, serialNumber string, holderName string, holderEmail string, customerID CustomerID) (Ticket, error) {
+	row := tq.DB.QueryRow(ctx, `
+		UPDATE ticket
+		SET serial_number = $2,
+			holder_name = NULLIF($3, ''),
+			holder_email = NULLIF($4, ''),
+			customer_id = NULLIF($6, 0),
+			updated_at = $5
+		WHERE id = $1
+		RETURNING id, serial_number, concert_id, ticket_category_id, COALESCE(order_id, 0),
+			COALESCE(reservation_id, 0), status, COALESCE(cancelled_at, 0), COALESCE(checked_in_at, 0),
+			COALESCE(holder_name, ''), COALESCE(holder_email, ''), COALESCE(customer_id, 0), COALESCE(seat_id, 0);
+	`, id, serialNumber, holderName, holderEmail, time.Now().Unix(), customerID)
+	var t Ticket
+	err := row.Scan(
+		&t.ID,
+		&t.SerialNumber,
+		&t.ConcertID,
+		&t.TicketCategoryID,
+		&t.OrderID,
+		&t.ReservationID,
+		&t.Status,
+		&t.CancelledAt,
+		&t.CheckedInAt,
+		&t.HolderName,
+		&t.HolderEmail,
+		&t.CustomerID,
+		&t.SeatID,
+	)
+	return t, err
+}
+
+func (tq *TicketQueryImpl) ListTicketHistory(ctx context.Context, id TicketID) ([]TicketStatusChange, error) {
+	rows, err := tq.DB.Query(ctx, `
+		SELECT
+			id,
+			ticket_id,
+			COALESCE(from_status, ''),
+			to_status,
+			actor,
+			created_at
+		FROM ticket_status_history
+		WHERE ticket_id = $1
+		ORDER BY id;
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	history := []TicketStatusChange{}
+	for rows.Next() {
+		var h TicketStatusChange
+		if err := rows.Scan(
+			&h.ID,
+			&h.TicketID,
+			&h.FromStatus,
+			&h.ToStatus,
+			&h.Actor,
+			&h.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Tells an unknown ticket apart from one without history.
+	if len(history) == 0 {
+		if _, err := tq.GetTicket(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
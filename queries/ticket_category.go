@@ -2,19 +2,37 @@ package queries
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log/slog"
+	"slices"
 	"time"
 )
 
 type TicketCategoryID = int
 
+var (
+	ErrTicketCategorySoldOut         = errors.New("ticket category sold out")
+	ErrTicketCategoryCapacityTooLow  = errors.New("ticket category capacity is lower than sold and held tickets")
+	ErrSaleNotStarted                = errors.New("ticket category is not on sale yet")
+	ErrTicketCategoryConcertMismatch = errors.New("ticket category does not belong to the concert")
+	ErrTicketCategoryInUse           = errors.New("ticket category has sold or held tickets")
+	ErrSaleEnded                     = errors.New("ticket category sale has ended")
+)
+
+// SectionIDs are the venue sections the seats come from, empty for general admission.
 type TicketCategory struct {
 	ID          int     `json:"id"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	ConcertID   int     `json:"concert_id"`
+	SectionIDs  []int   `json:"section_ids,omitempty"`
 	StartDate   int     `json:"start_date"`
 	EndDate     int     `json:"end_date"`
+	Capacity    int     `json:"capacity"`
+	Sold        int     `json:"sold"`
+	Held        int     `json:"held"`
+	Remaining   int     `json:"remaining"`
 	CreatedAt   int     `json:"created_at"`
 	UpdatedAt   int     `json:"updated_at"`
 }
@@ -25,22 +43,132 @@ func (tc TicketCategory) LogValue() slog.Value {
 		slog.String("description", tc.Description),
 		slog.Float64("price", tc.Price),
 		slog.Int("concert_id", tc.ConcertID),
+		slog.Int("remaining", tc.Remaining),
 	)
 }
 
+// Reports whether the category can be bought at the given time.
+// A zero start or end date leaves that side of the window open.
+func (tc TicketCategory) CheckSaleWindow(now time.Time) error {
+	if tc.StartDate > 0 && now.Unix() < int64(tc.StartDate) {
+		return ErrSaleNotStarted
+	}
+	if tc.EndDate > 0 && now.Unix() >= int64(tc.EndDate) {
+		return ErrSaleEnded
+	}
+	return nil
+}
+
+// Selects the section IDs of the ticket_category row, in ID order.
+const ticketCategorySections = `ARRAY(
+	SELECT section_id FROM ticket_category_section
+	WHERE ticket_category_id = ticket_category.id
+	ORDER BY section_id
+)`
+
+// Sorted and without duplicates, which also keeps the section locks in a stable order.
+func uniqueSections(sectionIDs []VenueSectionID) []VenueSectionID {
+	ids := slices.Clone(sectionIDs)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
 type TicketCategoryQueryImpl struct {
 	DB DbTx
 }
 
+// Fails with sql.ErrNoRows unless the concert belongs to the organizer.
+// A zero organizerID stands for platform admins and owns every concert.
+func (tc *TicketCategoryQueryImpl) checkConcertOrganizer(ctx context.Context, concertID ConcertID, organizerID OrganizerID) error {
+	if organizerID == 0 {
+		return nil
+	}
+	var id ConcertID
+	return tc.DB.QueryRow(ctx, `
+		SELECT id FROM concert WHERE id = $1 AND organizer_id = $2;
+	`, concertID, organizerID).Scan(&id)
+}
+
+// Fails with ErrVenueSectionVenueMismatch unless every section belongs to the concert venue,
+// and with ErrVenueSectionCapacityReached when the concert's categories sharing any of the sections,
+// other than exclude, leave no room for capacity in them. The sections stay locked until the transaction ends.
+func (tc *TicketCategoryQueryImpl) checkSections(ctx context.Context, concertID ConcertID, sectionIDs []VenueSectionID, exclude TicketCategoryID, capacity int) error {
+	if len(sectionIDs) == 0 {
+		return nil
+	}
+	var found, sectionCapacity, allocated int
+	err := tc.DB.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(capacity), 0) FROM (
+			SELECT vs.capacity
+			FROM venue_section vs
+			JOIN concert c ON c.venue_id = vs.venue_id
+			WHERE vs.id = ANY($1) AND c.id = $2
+			ORDER BY vs.id
+			FOR UPDATE OF vs
+		) locked;
+	`, sectionIDs, concertID).Scan(&found, &sectionCapacity)
+	if err != nil {
+		return err
+	}
+	if found != len(sectionIDs) {
+		return ErrVenueSectionVenueMismatch
+	}
+	err = tc.DB.QueryRow(ctx, `
+		SELECT COALESCE(SUM(tc.capacity), 0)
+		FROM ticket_category tc
+		WHERE tc.concert_id = $1 AND tc.id <> $3
+			AND EXISTS (
+				SELECT 1 FROM ticket_category_section tcs
+				WHERE tcs.ticket_category_id = tc.id AND tcs.section_id = ANY($2)
+			);
+	`, concertID, sectionIDs, exclude).Scan(&allocated)
+	if err != nil {
+		return err
+	}
+	if allocated+capacity > sectionCapacity {
+		return ErrVenueSectionCapacityReached
+	}
+	return nil
+}
+
+// Replaces the sections of the category.
+func (tc *TicketCategoryQueryImpl) setSections(ctx context.Context, id TicketCategoryID, sectionIDs []VenueSectionID) error {
+	if _, err := tc.DB.Exec(ctx, `
+		DELETE FROM ticket_category_section WHERE ticket_category_id = $1;
+	`, id); err != nil {
+		return err
+	}
+	if len(sectionIDs) == 0 {
+		return nil
+	}
+	_, err := tc.DB.Exec(ctx, `
+		INSERT INTO ticket_category_section (ticket_category_id, section_id)
+		SELECT $1, unnest($2::int[]);
+	`, id, sectionIDs)
+	return err
+}
+
 type CreateTicketCategoryArgs struct {
 	ConcertID   int
 	Description string
 	Price       float64
 	StartDate   int
 	EndDate     int
+	Capacity    int
+	// Venue sections the seats come from, none for general admission.
+	SectionIDs []VenueSectionID
+	// Limits the concert to the organizer's, zero for none.
+	OrganizerID OrganizerID
 }
 
 func (tc *TicketCategoryQueryImpl) CreateTicketCategory(ctx context.Context, args CreateTicketCategoryArgs) (TicketCategory, error) {
+	if err := tc.checkConcertOrganizer(ctx, args.ConcertID, args.OrganizerID); err != nil {
+		return TicketCategory{}, err
+	}
+	args.SectionIDs = uniqueSections(args.SectionIDs)
+	if err := tc.checkSections(ctx, args.ConcertID, args.SectionIDs, 0, args.Capacity); err != nil {
+		return TicketCategory{}, err
+	}
 	row := tc.DB.QueryRow(ctx, `
 		INSERT INTO ticket_category (
 			concert_id,
@@ -48,6 +176,7 @@ func (tc *TicketCategoryQueryImpl) CreateTicketCategory(ctx context.Context, arg
 			price,
 			start_date,
 			end_date,
+			capacity,
 			created_at,
 			updated_at
 		) VALUES (
@@ -57,16 +186,190 @@ func (tc *TicketCategoryQueryImpl) CreateTicketCategory(ctx context.Context, arg
 			$4,
 			$5,
 			$6,
-			$7 
-		) RETURNING id, concert_id, description, price;
-	`, args.ConcertID, args.Description, args.Price, args.StartDate, args.EndDate, time.Now().Unix(), time.Now().Unix())
+			$7,
+			$8
+		) RETURNING id, concert_id, description, price, capacity, sold, held;
+	`, args.ConcertID, args.Description, args.Price, args.StartDate, args.EndDate, args.Capacity, time.Now().Unix(), time.Now().Unix())
+	var tcat TicketCategory
+	err := row.Scan(
+		&tcat.ID,
+		&tcat.ConcertID,
+		&tcat.Description,
+		&tcat.Price,
+		&tcat.Capacity,
+		&tcat.Sold,
+		&tcat.Held,
+	)
+	if err != nil {
+		return tcat, err
+	}
+	if err := tc.setSections(ctx, tcat.ID, args.SectionIDs); err != nil {
+		return tcat, err
+	}
+	tcat.SectionIDs = args.SectionIDs
+	tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
+	return tcat, nil
+}
+
+func (tc *TicketCategoryQueryImpl) GetTicketCategory(ctx context.Context, id TicketCategoryID) (TicketCategory, error) {
+	row := tc.DB.QueryRow(ctx, `
+		SELECT
+			id,
+			concert_id,
+			`+ticketCategorySections+`,
+			description,
+			price,
+			start_date,
+			end_date,
+			capacity,
+			sold,
+			held
+		FROM ticket_category
+		WHERE id = $1;
+	`, id)
+	var tcat TicketCategory
+	err := row.Scan(
+		&tcat.ID,
+		&tcat.ConcertID,
+		&tcat.SectionIDs,
+		&tcat.Description,
+		&tcat.Price,
+		&tcat.StartDate,
+		&tcat.EndDate,
+		&tcat.Capacity,
+		&tcat.Sold,
+		&tcat.Held,
+	)
+	tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
+	return tcat, err
+}
+
+func (tc *TicketCategoryQueryImpl) ListTicketCategories(ctx context.Context, concertID ConcertID, organizerID OrganizerID) ([]TicketCategory, error) {
+	if err := tc.checkConcertOrganizer(ctx, concertID, organizerID); err != nil {
+		return nil, err
+	}
+	rows, err := tc.DB.Query(ctx, `
+		SELECT
+			id,
+			concert_id,
+			`+ticketCategorySections+`,
+			description,
+			price,
+			start_date,
+			end_date,
+			capacity,
+			sold,
+			held
+		FROM ticket_category
+		WHERE concert_id = $1
+		ORDER BY id;
+	`, concertID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tcats := []TicketCategory{}
+	for rows.Next() {
+		var tcat TicketCategory
+		if err := rows.Scan(
+			&tcat.ID,
+			&tcat.ConcertID,
+			&tcat.SectionIDs,
+			&tcat.Description,
+			&tcat.Price,
+			&tcat.StartDate,
+			&tcat.EndDate,
+			&tcat.Capacity,
+			&tcat.Sold,
+			&tcat.Held,
+		); err != nil {
+			return nil, err
+		}
+		tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
+		tcats = append(tcats, tcat)
+	}
+	return tcats, rows.Err()
+}
+
+// Categories of a concert that are on sale at the given time and not sold out.
+func (tc *TicketCategoryQueryImpl) ListPurchasableTicketCategories(ctx context.Context, concertID ConcertID, now time.Time) ([]TicketCategory, error) {
+	rows, err := tc.DB.Query(ctx, `
+		SELECT
+			id,
+			concert_id,
+			`+ticketCategorySections+`,
+			description,
+			price,
+			start_date,
+			end_date,
+			capacity,
+			sold,
+			held
+		FROM ticket_category
+		WHERE concert_id = $1
+			AND (start_date = 0 OR start_date <= $2)
+			AND (end_date = 0 OR end_date > $2)
+			AND capacity - sold - held > 0
+		ORDER BY price, id;
+	`, concertID, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tcats := []TicketCategory{}
+	for rows.Next() {
+		var tcat TicketCategory
+		if err := rows.Scan(
+			&tcat.ID,
+			&tcat.ConcertID,
+			&tcat.SectionIDs,
+			&tcat.Description,
+			&tcat.Price,
+			&tcat.StartDate,
+			&tcat.EndDate,
+			&tcat.Capacity,
+			&tcat.Sold,
+			&tcat.Held,
+		); err != nil {
+			return nil, err
+		}
+		tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
+		tcats = append(tcats, tcat)
+	}
+	return tcats, rows.Err()
+}
+
+// Atomically moves the sold and held counters of a category by the given deltas.
+// Returns ErrTicketCategorySoldOut when the result would exceed the category capacity.
+func (tc *TicketCategoryQueryImpl) AdjustTicketCategoryInventory(ctx context.Context, id TicketCategoryID, soldDelta int, heldDelta int) (TicketCategory, error) {
+	row := tc.DB.QueryRow(ctx, `
+		UPDATE ticket_category
+		SET sold = sold + $2,
+			held = held + $3,
+			updated_at = $4
+		WHERE id = $1
+			AND sold + $2 >= 0
+			AND held + $3 >= 0
+			AND sold + $2 + held + $3 <= capacity
+		RETURNING id, concert_id, description, price, capacity, sold, held;
+	`, id, soldDelta, heldDelta, time.Now().Unix())
 	var tcat TicketCategory
 	err := row.Scan(
 		&tcat.ID,
 		&tcat.ConcertID,
 		&tcat.Description,
 		&tcat.Price,
+		&tcat.Capacity,
+		&tcat.Sold,
+		&tcat.Held,
 	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := tc.GetTicketCategory(ctx, id); err != nil {
+			return tcat, err
+		}
+		return tcat, ErrTicketCategorySoldOut
+	}
+	tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
 	return tcat, err
 }
 
@@ -77,19 +380,44 @@ type UpdateTicketCategoryArgs struct {
 	Price       float64
 	StartDate   int
 	EndDate     int
+	Capacity    int
+	// Venue sections the seats come from, none for general admission.
+	SectionIDs []VenueSectionID
+	// Limits both the current and the new concert to the organizer's, zero for none.
+	OrganizerID OrganizerID
 }
 
 func (tc *TicketCategoryQueryImpl) UpdateTicketCategory(ctx context.Context, args UpdateTicketCategoryArgs) (TicketCategory, error) {
+	if args.OrganizerID > 0 {
+		current, err := tc.GetTicketCategory(ctx, args.ID)
+		if err != nil {
+			return TicketCategory{}, err
+		}
+		if err := tc.checkConcertOrganizer(ctx, current.ConcertID, args.OrganizerID); err != nil {
+			return TicketCategory{}, err
+		}
+		if err := tc.checkConcertOrganizer(ctx, args.ConcertID, args.OrganizerID); err != nil {
+			return TicketCategory{}, err
+		}
+	}
+	args.SectionIDs = uniqueSections(args.SectionIDs)
+	if err := tc.checkSections(ctx, args.ConcertID, args.SectionIDs, args.ID, args.Capacity); err != nil {
+		return TicketCategory{}, err
+	}
 	row := tc.DB.QueryRow(ctx, `
 		UPDATE ticket_category
 		SET concert_id = $1,
 			description = $2,
 			price = $3,
 			start_date = $4,
-			end_date = $5
-		WHERE id = $6
-		RETURNING id, concert_id, description, price, start_date, end_date;
-	`, args.ConcertID, args.Description, args.Price, args.StartDate, args.EndDate, args.ID)
+			end_date = $5,
+			capacity = $6,
+			updated_at = $8
+		WHERE id = $7
+			AND sold + held <= $6
+			AND (concert_id = $1 OR sold + held = 0)
+		RETURNING id, concert_id, description, price, start_date, end_date, capacity, sold, held;
+	`, args.ConcertID, args.Description, args.Price, args.StartDate, args.EndDate, args.Capacity, args.ID, time.Now().Unix())
 	var tcat TicketCategory
 	err := row.Scan(
 		&tcat.ID,
@@ -98,16 +426,48 @@ func (tc *TicketCategoryQueryImpl) UpdateTicketCategory(ctx context.Context, arg
 		&tcat.Price,
 		&tcat.StartDate,
 		&tcat.EndDate,
+		&tcat.Capacity,
+		&tcat.Sold,
+		&tcat.Held,
 	)
-	return tcat, err
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		current, err := tc.GetTicketCategory(ctx, args.ID)
+		if err != nil {
+			return tcat, err
+		}
+		// Issued tickets and holds keep pointing at the current concert.
+		if current.ConcertID != args.ConcertID && current.Sold+current.Held > 0 {
+			return tcat, ErrTicketCategoryInUse
+		}
+		return tcat, ErrTicketCategoryCapacityTooLow
+	}
+	if err != nil {
+		return tcat, err
+	}
+	if err := tc.setSections(ctx, tcat.ID, args.SectionIDs); err != nil {
+		return tcat, err
+	}
+	tcat.SectionIDs = args.SectionIDs
+	tcat.Remaining = tcat.Capacity - tcat.Sold - tcat.Held
+	return tcat, nil
 }
 
-func (tc *TicketCategoryQueryImpl) DeleteTicketCategory(ctx context.Context, id TicketCategoryID) (TicketCategory, error) {
+// A non-zero organizerID limits the delete to categories of that organizer's concerts.
+func (tc *TicketCategoryQueryImpl) DeleteTicketCategory(ctx context.Context, id TicketCategoryID, organizerID OrganizerID) (TicketCategory, error) {
+	if organizerID > 0 {
+		current, err := tc.GetTicketCategory(ctx, id)
+		if err != nil {
+			return TicketCategory{}, err
+		}
+		if err := tc.checkConcertOrganizer(ctx, current.ConcertID, organizerID); err != nil {
+			return TicketCategory{}, err
+		}
+	}
 	row := tc.DB.QueryRow(ctx, `
 		DELETE FROM ticket_category
 		WHERE id = $1
 		RETURNING id, description, price;
-	`)
+	`, id)
 	var tcat TicketCategory
 	err := row.Scan(
 		&tcat.ID,
@@ -0,0 +1,223 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type TicketTransferID = int
+
+type TicketTransferStatus = string
+
+const (
+	TicketTransferPending   TicketTransferStatus = "pending"
+	TicketTransferAccepted  TicketTransferStatus = "accepted"
+	TicketTransferCancelled TicketTransferStatus = "cancelled"
+	TicketTransferExpired   TicketTransferStatus = "expired"
+)
+
+var (
+	ErrTicketTransferPending    = errors.New("ticket already has a pending transfer")
+	ErrTicketTransferNotPending = errors.New("ticket transfer is no longer pending")
+	ErrTicketTransferExpired    = errors.New("ticket transfer expired")
+	ErrTicketTransferCode       = errors.New("invalid ticket transfer code")
+)
+
+type TicketTransfer struct {
+	ID        TicketTransferID     `json:"id"`
+	TicketID  TicketID             `json:"ticket_id"`
+	Status    TicketTransferStatus `json:"status"`
+	FromName  string               `json:"from_name,omitempty"`
+	FromEmail string               `json:"from_email"`
+	ToName    string               `json:"to_name,omitempty"`
+	ToEmail   string               `json:"to_email"`
+	// Serial numbers before and after the transfer, the latter set once accepted.
+	FromSerialNumber string `json:"from_serial_number"`
+	ToSerialNumber   string `json:"to_serial_number,omitempty"`
+	ExpiresAt        int    `json:"expires_at"`
+	AcceptedAt       int    `json:"accepted_at,omitempty"`
+	CreatedAt        int    `json:"created_at"`
+	UpdatedAt        int    `json:"updated_at"`
+}
+
+func (t TicketTransfer) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", t.ID),
+		slog.Int("ticket_id", t.TicketID),
+		slog.String("status", t.Status),
+	)
+}
+
+type TicketTransferQueryImpl struct {
+	DB DbTx
+}
+
+const ticketTransferColumns = `id, ticket_id, status, COALESCE(from_name, ''), from_email, COALESCE(to_name, ''), to_email,
+	from_serial_number, COALESCE(to_serial_number::text, ''), expires_at, COALESCE(accepted_at, 0), created_at, updated_at`
+
+func scanTicketTransfer(row interface{ Scan(dest ...any) error }) (TicketTransfer, error) {
+	var t TicketTransfer
+	err := row.Scan(
+		&t.ID,
+		&t.TicketID,
+		&t.Status,
+		&t.FromName,
+		&t.FromEmail,
+		&t.ToName,
+		&t.ToEmail,
+		&t.FromSerialNumber,
+		&t.ToSerialNumber,
+		&t.ExpiresAt,
+		&t.AcceptedAt,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	return t, err
+}
+
+type CreateTicketTransferArgs struct {
+	TicketID         TicketID
+	FromName         string
+	FromEmail        string
+	ToEmail          string
+	FromSerialNumber string
+	// SHA-256 of the code the recipient accepts with, the code itself is never stored.
+	CodeHash  string
+	ExpiresAt int
+}
+
+// Returns ErrTicketTransferPending when the ticket already has a pending transfer.
+func (tq *TicketTransferQueryImpl) CreateTicketTransfer(ctx context.Context, args CreateTicketTransferArgs) (TicketTransfer, error) {
+	now := time.Now().Unix()
+	// Lapsed transfers must not block a new one.
+	if _, err := tq.DB.Exec(ctx, `
+		UPDATE ticket_transfer
+		SET status = $2, updated_at = $3
+		WHERE ticket_id = $1 AND status = $4 AND expires_at <= $3;
+	`, args.TicketID, TicketTransferExpired, now, TicketTransferPending); err != nil {
+		return TicketTransfer{}, err
+	}
+	row := tq.DB.QueryRow(ctx, `
+		INSERT INTO ticket_transfer (
+			ticket_id,
+			status,
+			from_name,
+			from_email,
+			to_email,
+			code_hash,
+			from_serial_number,
+			expires_at,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $9
+		)
+		ON CONFLICT (ticket_id) WHERE status = 'pending' DO NOTHING
+		RETURNING `+ticketTransferColumns+`;
+	`, args.TicketID, TicketTransferPending, args.FromName, args.FromEmail, args.ToEmail, args.CodeHash,
+		args.FromSerialNumber, args.ExpiresAt, now)
+	t, err := scanTicketTransfer(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return t, ErrTicketTransferPending
+	}
+	return t, err
+}
+
+func (tq *TicketTransferQueryImpl) GetTicketTransfer(ctx context.Context, id TicketTransferID) (TicketTransfer, error) {
+	row := tq.DB.QueryRow(ctx, `
+		SELECT `+ticketTransferColumns+`
+		FROM ticket_transfer
+		WHERE id = $1;
+	`, id)
+	return scanTicketTransfer(row)
+}
+
+type AcceptTicketTransferArgs struct {
+	ID             TicketTransferID
+	CodeHash       string
+	ToName         string
+	ToSerialNumber string
+}
+
+// Marks a pending transfer accepted when the code matches and it has not expired.
+// The reason for a refusal is told apart after the fact, like the other conditional updates.
+func (tq *TicketTransferQueryImpl) AcceptTicketTransfer(ctx context.Context, args AcceptTicketTransferArgs) (TicketTransfer, error) {
+	now := time.Now().Unix()
+	row := tq.DB.QueryRow(ctx, `
+		UPDATE ticket_transfer
+		SET status = $2,
+			to_name = NULLIF($3, ''),
+			to_serial_number = $4,
+			accepted_at = $5,
+			updated_at = $5
+		WHERE id = $1
+			AND status = $6
+			AND code_hash = $7
+			AND expires_at > $5
+		RETURNING `+ticketTransferColumns+`;
+	`, args.ID, TicketTransferAccepted, args.ToName, args.ToSerialNumber, now, TicketTransferPending, args.CodeHash)
+	t, err := scanTicketTransfer(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		current, err := tq.GetTicketTransfer(ctx, args.ID)
+		if err != nil {
+			return current, err
+		}
+		switch {
+		case current.Status != TicketTransferPending:
+			return current, ErrTicketTransferNotPending
+		case int64(current.ExpiresAt) <= now:
+			return current, ErrTicketTransferExpired
+		default:
+			return current, ErrTicketTransferCode
+		}
+	}
+	return t, err
+}
+
+// Withdraws a pending transfer, only on behalf of the holder who started it.
+func (tq *TicketTransferQueryImpl) CancelTicketTransfer(ctx context.Context, id TicketTransferID, fromEmail string) (TicketTransfer, error) {
+	row := tq.DB.QueryRow(ctx, `
+		UPDATE ticket_transfer
+		SET status = $2, updated_at = $3
+		WHERE id = $1 AND status = $4 AND from_email = $5
+		RETURNING `+ticketTransferColumns+`;
+	`, id, TicketTransferCancelled, time.Now().Unix(), TicketTransferPending, fromEmail)
+	t, err := scanTicketTransfer(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		current, err := tq.GetTicketTransfer(ctx, id)
+		if err != nil {
+			return current, err
+		}
+		if current.Status != TicketTransferPending {
+			return current, ErrTicketTransferNotPending
+		}
+		// Somebody else's transfer looks the same as a missing one.
+		return current, sql.ErrNoRows
+	}
+	return t, err
+}
+
+func (tq *TicketTransferQueryImpl) ListTicketTransfers(ctx context.Context, ticketID TicketID) ([]TicketTransfer, error) {
+	rows, err := tq.DB.Query(ctx, `
+		SELECT `+ticketTransferColumns+`
+		FROM ticket_transfer
+		WHERE ticket_id = $1
+		ORDER BY id;
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	transfers := []TicketTransfer{}
+	for rows.Next() {
+		t, err := scanTicketTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
@@ -0,0 +1,170 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type VenueID = int
+
+var (
+	ErrVenueInUse          = errors.New("venue has concerts")
+	ErrVenueCapacityTooLow = errors.New("venue capacity is lower than its sections or concert limits")
+)
+
+type Venue struct {
+	ID      VenueID `json:"id"`
+	Name    string  `json:"name"`
+	Address string  `json:"address,omitempty"`
+	City    string  `json:"city,omitempty"`
+	// Zero when the capacity is unknown and not enforced.
+	Capacity  int `json:"capacity"`
+	CreatedAt int `json:"created_at,omitempty"`
+	UpdatedAt int `json:"updated_at,omitempty"`
+}
+
+func (v Venue) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", v.ID),
+		slog.String("name", v.Name),
+		slog.String("city", v.City),
+		slog.Int("capacity", v.Capacity),
+	)
+}
+
+type VenueQueryImpl struct {
+	DB DbTx
+}
+
+const venueColumns = `id, name, COALESCE(address, ''), COALESCE(city, ''), capacity, created_at, updated_at`
+
+func scanVenue(row interface{ Scan(dest ...any) error }) (Venue, error) {
+	var v Venue
+	err := row.Scan(
+		&v.ID,
+		&v.Name,
+		&v.Address,
+		&v.City,
+		&v.Capacity,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+	return v, err
+}
+
+type CreateVenueArgs struct {
+	Name     string
+	Address  string
+	City     string
+	Capacity int
+}
+
+func (vq *VenueQueryImpl) CreateVenue(ctx context.Context, args CreateVenueArgs) (Venue, error) {
+	row := vq.DB.QueryRow(ctx, `
+		INSERT INTO venue (
+			name,
+			address,
+			city,
+			capacity,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $5
+		) RETURNING `+venueColumns+`;
+	`, args.Name, args.Address, args.City, args.Capacity, time.Now().Unix())
+	return scanVenue(row)
+}
+
+func (vq *VenueQueryImpl) GetVenue(ctx context.Context, id VenueID) (Venue, error) {
+	row := vq.DB.QueryRow(ctx, `
+		SELECT `+venueColumns+`
+		FROM venue
+		WHERE id = $1;
+	`, id)
+	return scanVenue(row)
+}
+
+func (vq *VenueQueryImpl) ListVenues(ctx context.Context) ([]Venue, error) {
+	rows, err := vq.DB.Query(ctx, `
+		SELECT `+venueColumns+`
+		FROM venue
+		ORDER BY name, id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	venues := []Venue{}
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		venues = append(venues, v)
+	}
+	return venues, rows.Err()
+}
+
+type UpdateVenueArgs struct {
+	ID VenueID
+	// Empty fields are left unchanged.
+	Name     string
+	Address  string
+	City     string
+	Capacity int
+}
+
+// Returns ErrVenueCapacityTooLow when the new capacity does not fit the venue's
+// sections or the limit of a concert held there.
+func (vq *VenueQueryImpl) UpdateVenue(ctx context.Context, args UpdateVenueArgs) (Venue, error) {
+	row := vq.DB.QueryRow(ctx, `
+		UPDATE venue
+		SET name = COALESCE(NULLIF($2, ''), name),
+			address = COALESCE(NULLIF($3, ''), address),
+			city = COALESCE(NULLIF($4, ''), city),
+			capacity = CASE WHEN $5 > 0 THEN $5 ELSE capacity END,
+			updated_at = $6
+		WHERE id = $1
+			AND (
+				$5 = 0
+				OR (
+					$5 >= (SELECT COALESCE(SUM(capacity), 0) FROM venue_section WHERE venue_id = $1)
+					AND $5 >= (SELECT COALESCE(MAX("limit"), 0) FROM concert WHERE venue_id = $1)
+				)
+			)
+		RETURNING `+venueColumns+`;
+	`, args.ID, args.Name, args.Address, args.City, args.Capacity, time.Now().Unix())
+	v, err := scanVenue(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := vq.GetVenue(ctx, args.ID); err != nil {
+			return v, err
+		}
+		return v, ErrVenueCapacityTooLow
+	}
+	return v, err
+}
+
+// Returns ErrVenueInUse while any concert still takes place at the venue.
+func (vq *VenueQueryImpl) DeleteVenue(ctx context.Context, id VenueID) (Venue, error) {
+	row := vq.DB.QueryRow(ctx, `
+		DELETE FROM venue
+		WHERE id = $1
+			AND NOT EXISTS (SELECT 1 FROM concert WHERE venue_id = $1)
+		RETURNING id, name;
+	`, id)
+	var v Venue
+	err := row.Scan(
+		&v.ID,
+		&v.Name,
+	)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := vq.GetVenue(ctx, id); err != nil {
+			return v, err
+		}
+		return v, ErrVenueInUse
+	}
+	return v, err
+}
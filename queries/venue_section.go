@@ -0,0 +1,235 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type VenueSectionID = int
+
+var (
+	ErrVenueSectionExists          = errors.New("venue already has a section with this name")
+	ErrVenueCapacityExceeded       = errors.New("venue sections exceed the venue capacity")
+	ErrVenueSectionInUse           = errors.New("venue section has ticket categories")
+	ErrVenueSectionCapacityTooLow  = errors.New("venue section capacity is lower than its seats or ticket categories")
+	ErrVenueSectionCapacityReached = errors.New("ticket categories exceed the venue section capacity")
+	ErrVenueSectionVenueMismatch   = errors.New("venue section does not belong to the concert venue")
+)
+
+// A part of a venue, such as the floor, a balcony or the boxes.
+// Seats are allocated from the section with the best priority first, 1 being the best
+// and zero leaving the section unranked behind every ranked one.
+type VenueSection struct {
+	ID        VenueSectionID `json:"id"`
+	VenueID   VenueID        `json:"venue_id"`
+	Name      string         `json:"name"`
+	Capacity  int            `json:"capacity"`
+	Priority  int            `json:"priority"`
+	CreatedAt int            `json:"created_at,omitempty"`
+	UpdatedAt int            `json:"updated_at,omitempty"`
+}
+
+func (vs VenueSection) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", vs.ID),
+		slog.Int("venue_id", vs.VenueID),
+		slog.String("name", vs.Name),
+		slog.Int("capacity", vs.Capacity),
+		slog.Int("priority", vs.Priority),
+	)
+}
+
+type VenueSectionQueryImpl struct {
+	DB DbTx
+}
+
+const venueSectionColumns = `id, venue_id, name, capacity, priority, created_at, updated_at`
+
+func scanVenueSection(row interface{ Scan(dest ...any) error }) (VenueSection, error) {
+	var vs VenueSection
+	err := row.Scan(
+		&vs.ID,
+		&vs.VenueID,
+		&vs.Name,
+		&vs.Capacity,
+		&vs.Priority,
+		&vs.CreatedAt,
+		&vs.UpdatedAt,
+	)
+	return vs, err
+}
+
+// Locks the venue for the rest of the transaction and fails with ErrVenueCapacityExceeded
+// when its other sections and the given capacity do not fit in it.
+func (vs *VenueSectionQueryImpl) checkVenueCapacity(ctx context.Context, venueID VenueID, exclude VenueSectionID, capacity int) error {
+	var venueCapacity, allocated int
+	err := vs.DB.QueryRow(ctx, `
+		SELECT capacity FROM venue WHERE id = $1 FOR UPDATE;
+	`, venueID).Scan(&venueCapacity)
+	if err != nil {
+		return err
+	}
+	if venueCapacity == 0 {
+		return nil
+	}
+	err = vs.DB.QueryRow(ctx, `
+		SELECT COALESCE(SUM(capacity), 0) FROM venue_section WHERE venue_id = $1 AND id <> $2;
+	`, venueID, exclude).Scan(&allocated)
+	if err != nil {
+		return err
+	}
+	if allocated+capacity > venueCapacity {
+		return ErrVenueCapacityExceeded
+	}
+	return nil
+}
+
+type CreateVenueSectionArgs struct {
+	VenueID  VenueID
+	Name     string
+	Capacity int
+	Priority int
+}
+
+// Run it in a transaction, the venue stays locked until commit so concurrent
+// sections cannot overrun the venue capacity together.
+func (vs *VenueSectionQueryImpl) CreateVenueSection(ctx context.Context, args CreateVenueSectionArgs) (VenueSection, error) {
+	if err := vs.checkVenueCapacity(ctx, args.VenueID, 0, args.Capacity); err != nil {
+		return VenueSection{}, err
+	}
+	row := vs.DB.QueryRow(ctx, `
+		INSERT INTO venue_section (
+			venue_id,
+			name,
+			capacity,
+			priority,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $5
+		)
+		ON CONFLICT (venue_id, name) DO NOTHING
+		RETURNING `+venueSectionColumns+`;
+	`, args.VenueID, args.Name, args.Capacity, args.Priority, time.Now().Unix())
+	section, err := scanVenueSection(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return section, ErrVenueSectionExists
+	}
+	return section, err
+}
+
+func (vs *VenueSectionQueryImpl) GetVenueSection(ctx context.Context, id VenueSectionID) (VenueSection, error) {
+	row := vs.DB.QueryRow(ctx, `
+		SELECT `+venueSectionColumns+`
+		FROM venue_section
+		WHERE id = $1;
+	`, id)
+	return scanVenueSection(row)
+}
+
+func (vs *VenueSectionQueryImpl) ListVenueSections(ctx context.Context, venueID VenueID) ([]VenueSection, error) {
+	rows, err := vs.DB.Query(ctx, `
+		SELECT `+venueSectionColumns+`
+		FROM venue_section
+		WHERE venue_id = $1
+		ORDER BY name, id;
+	`, venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sections := []VenueSection{}
+	for rows.Next() {
+		section, err := scanVenueSection(rows)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, rows.Err()
+}
+
+type UpdateVenueSectionArgs struct {
+	ID VenueSectionID
+	// Empty fields are left unchanged.
+	Name     string
+	Capacity int
+	Priority int
+}
+
+// Like CreateVenueSection it belongs in a transaction. The new capacity must still
+// hold the section's seats and the ticket categories every concert has mapped to it.
+func (vs *VenueSectionQueryImpl) UpdateVenueSection(ctx context.Context, args UpdateVenueSectionArgs) (VenueSection, error) {
+	current, err := vs.GetVenueSection(ctx, args.ID)
+	if err != nil {
+		return current, err
+	}
+	if args.Capacity > 0 {
+		if err := vs.checkVenueCapacity(ctx, current.VenueID, current.ID, args.Capacity); err != nil {
+			return VenueSection{}, err
+		}
+	}
+	row := vs.DB.QueryRow(ctx, `
+		UPDATE venue_section
+		SET name = COALESCE(NULLIF($2, ''), name),
+			capacity = CASE WHEN $3 > 0 THEN $3 ELSE capacity END,
+			priority = CASE WHEN $5 > 0 THEN $5 ELSE priority END,
+			updated_at = $4
+		WHERE id = $1
+			AND (
+				$3 = 0
+				OR (
+					$3 >= (SELECT COUNT(*) FROM seat WHERE section_id = $1)
+					-- Every category in the section must still fit, together with the categories
+					-- sharing its sections, in the room of its sections.
+					AND NOT EXISTS (
+						SELECT 1
+						FROM ticket_category tc
+						JOIN ticket_category_section mapped ON mapped.ticket_category_id = tc.id AND mapped.section_id = $1
+						WHERE (
+							SELECT SUM(CASE WHEN vs.id = $1 THEN $3 ELSE vs.capacity END)
+							FROM ticket_category_section own
+							JOIN venue_section vs ON vs.id = own.section_id
+							WHERE own.ticket_category_id = tc.id
+						) < (
+							SELECT SUM(other.capacity)
+							FROM ticket_category other
+							WHERE other.concert_id = tc.concert_id AND EXISTS (
+								SELECT 1
+								FROM ticket_category_section a
+								JOIN ticket_category_section b ON b.section_id = a.section_id
+								WHERE a.ticket_category_id = tc.id AND b.ticket_category_id = other.id
+							)
+						)
+					)
+				)
+			)
+		RETURNING `+venueSectionColumns+`;
+	`, args.ID, args.Name, args.Capacity, time.Now().Unix(), args.Priority)
+	section, err := scanVenueSection(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return section, ErrVenueSectionCapacityTooLow
+	}
+	return section, err
+}
+
+// Returns ErrVenueSectionInUse while ticket categories are mapped to the section.
+func (vs *VenueSectionQueryImpl) DeleteVenueSection(ctx context.Context, id VenueSectionID) (VenueSection, error) {
+	row := vs.DB.QueryRow(ctx, `
+		DELETE FROM venue_section
+		WHERE id = $1
+			AND NOT EXISTS (SELECT 1 FROM ticket_category_section WHERE section_id = $1)
+		RETURNING `+venueSectionColumns+`;
+	`, id)
+	section, err := scanVenueSection(row)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		if _, err := vs.GetVenueSection(ctx, id); err != nil {
+			return section, err
+		}
+		return section, ErrVenueSectionInUse
+	}
+	return section, err
+}
@@ -1,6 +1,10 @@
 import http from "k6/http";
 import { sleep, check } from "k6";
 
+// Run the server with PURCHASE_STRATEGY=redsync or PURCHASE_STRATEGY=db
+// to compare both oversell protection strategies.
+// Purchases need a customer, pass one with `k6 run -e TOKEN=<jwt from POST /auth/token>`.
+
 // Simulate 500 users.
 export const options = {
     vus: 500,
@@ -12,7 +16,10 @@ export default function () {
         concert_id: 1,
         ticket_category: 1,
     });
-    const headers = { "Content-Type": "application/json" };
+    const headers = {
+        "Content-Type": "application/json",
+        Authorization: `Bearer ${__ENV.TOKEN}`,
+    };
     const res = http.post("http://localhost:8080/ticket/", payload, {
         headers,
     });
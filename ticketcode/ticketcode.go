@@ -0,0 +1,162 @@
+// Package ticketcode renders ticket serial numbers and tokens as QR codes and Code128 barcodes.
+//
+// Everything is drawn in process, so tickets can be embedded in emails and wallet passes
+// without calling an external service.
+package ticketcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+type Symbology = string
+
+const (
+	SymbologyQR      Symbology = "qr"
+	SymbologyCode128 Symbology = "code128"
+)
+
+type Format = string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+var (
+	ErrUnknownSymbology = errors.New("unknown symbology, expected qr or code128")
+	ErrUnknownFormat    = errors.New("unknown format, expected png or svg")
+)
+
+// Blank modules kept around the code so scanners can find its edges.
+const quietZone = 4
+
+// Height of a Code128 barcode relative to its module width, absent an explicit size.
+const barHeightRatio = 0.3
+
+// Render draws content in the given symbology and format.
+// Size is the width in pixels, the height follows the symbology (square for QR).
+func Render(content string, symbology Symbology, format Format, size int) ([]byte, error) {
+	bc, err := encode(content, symbology)
+	if err != nil {
+		return nil, err
+	}
+	width := bc.Bounds().Dx() + 2*quietZone
+	if size < width {
+		size = width
+	}
+	// Whole pixels per module keep the edges sharp.
+	size -= size % width
+	height := size
+	if symbology == SymbologyCode128 {
+		height = int(float64(size) * barHeightRatio)
+	}
+	switch format {
+	case FormatPNG:
+		return renderPNG(bc, size, height)
+	case FormatSVG:
+		return renderSVG(bc, size, height), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func ContentType(format Format) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+func encode(content string, symbology Symbology) (barcode.Barcode, error) {
+	switch symbology {
+	case SymbologyQR:
+		// Medium correction survives scuffed screens and printouts without bloating the code.
+		return qr.Encode(content, qr.M, qr.Auto)
+	case SymbologyCode128:
+		return code128.Encode(content)
+	default:
+		return nil, ErrUnknownSymbology
+	}
+}
+
+func renderPNG(bc barcode.Barcode, width int, height int) ([]byte, error) {
+	b := bc.Bounds()
+	scale := width / (b.Dx() + 2*quietZone)
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !dark(bc.At(x, y)) {
+				continue
+			}
+			module := image.Rect(0, 0, scale, scale).Add(image.Pt(
+				(x-b.Min.X+quietZone)*scale,
+				(y-b.Min.Y+quietZone)*scale,
+			))
+			// Bars of a 1D code run the full height.
+			if b.Dy() == 1 {
+				module.Min.Y, module.Max.Y = 0, height
+			}
+			draw.Draw(img, module, image.Black, image.Point{}, draw.Src)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bc barcode.Barcode, width int, height int) []byte {
+	b := bc.Bounds()
+	modulesX := b.Dx() + 2*quietZone
+	modulesY := b.Dy() + 2*quietZone
+	oneD := b.Dy() == 1
+	if oneD {
+		modulesY = 1
+	}
+	// The one module high bars of a 1D code are stretched to the full height.
+	aspect := ""
+	if oneD {
+		aspect = ` preserveAspectRatio="none"`
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"%s shape-rendering="crispEdges">`,
+		width, height, modulesX, modulesY, aspect)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modulesX, modulesY)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		// Runs of dark modules become a single rectangle.
+		for x := b.Min.X; x < b.Max.X; {
+			if !dark(bc.At(x, y)) {
+				x++
+				continue
+			}
+			start := x
+			for x < b.Max.X && dark(bc.At(x, y)) {
+				x++
+			}
+			top := y - b.Min.Y + quietZone
+			if oneD {
+				top = 0
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start-b.Min.X+quietZone, top, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func dark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
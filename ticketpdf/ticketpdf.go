@@ -0,0 +1,133 @@
+// Package ticketpdf lays out printable tickets, one ticket per page.
+package ticketpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/hendrywilliam/gate-keeper/queries"
+	"github.com/hendrywilliam/gate-keeper/ticketcode"
+)
+
+// Everything printed on a single ticket.
+type Entry struct {
+	Concert  queries.Concert
+	Category queries.TicketCategory
+	Ticket   queries.Ticket
+}
+
+// Page geometry in millimetres, sized to fold into a DL envelope.
+const (
+	pageWidth  = 210.0
+	pageHeight = 99.0
+	margin     = 8.0
+	qrSize     = 60.0
+	// Pixels of the embedded QR image, plenty for print at qrSize.
+	qrPixels = 600
+)
+
+// Render writes a PDF with one page per entry to w, concert dates shown in loc.
+func Render(w io.Writer, entries []Entry, loc *time.Location) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "L",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: pageHeight, Ht: pageWidth},
+	})
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	pdf.SetTitle("Tickets", true)
+	pdf.SetCreator("gate-keeper", true)
+	// Core fonts are cp1252, concert names are not.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	for _, e := range entries {
+		if err := page(pdf, tr, e, loc); err != nil {
+			return err
+		}
+	}
+	return pdf.Output(w)
+}
+
+func page(pdf *fpdf.Fpdf, tr func(string) string, e Entry, loc *time.Location) error {
+	pdf.AddPage()
+	pdf.SetDrawColor(40, 40, 40)
+	pdf.SetLineWidth(0.4)
+	pdf.Rect(margin/2, margin/2, pageWidth-margin, pageHeight-margin, "D")
+
+	// Stub on the right holds the code the gate scans.
+	stubX := pageWidth - margin - qrSize
+	pdf.SetDashPattern([]float64{2, 1.5}, 0)
+	pdf.Line(stubX-margin/2, margin/2, stubX-margin/2, pageHeight-margin/2)
+	pdf.SetDashPattern([]float64{}, 0)
+
+	// Tickets that cannot get in anymore carry no token, the serial still identifies them.
+	content := e.Ticket.Token
+	if content == "" {
+		content = e.Ticket.SerialNumber
+	}
+	qr, err := ticketcode.Render(content, ticketcode.SymbologyQR, ticketcode.FormatPNG, qrPixels)
+	if err != nil {
+		return err
+	}
+	name := "qr-" + e.Ticket.SerialNumber
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions(name, stubX, margin, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetFont("Helvetica", "", 6)
+	pdf.SetXY(stubX, margin+qrSize+1)
+	pdf.MultiCell(qrSize, 3, e.Ticket.SerialNumber, "", "C", false)
+
+	width := stubX - 2*margin
+	pdf.SetXY(margin, margin+2)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(width, 9, tr(e.Concert.Name), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 11)
+	if e.Concert.Artist != nil {
+		pdf.SetX(margin)
+		pdf.CellFormat(width, 7, tr(e.Concert.Artist.Name), "", 1, "L", false, 0, "")
+	}
+	pdf.SetX(margin)
+	pdf.CellFormat(width, 7, formatDate(e.Concert.Date, loc), "", 1, "L", false, 0, "")
+	if e.Concert.Venue != nil {
+		pdf.SetX(margin)
+		pdf.CellFormat(width, 7, tr(formatVenue(*e.Concert.Venue)), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetX(margin)
+	pdf.CellFormat(width, 8, tr(e.Category.Description), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetX(margin)
+	pdf.CellFormat(width, 6, fmt.Sprintf("Price: %.2f", e.Category.Price), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetXY(margin, pageHeight-margin-10)
+	pdf.MultiCell(width, 4, fmt.Sprintf(
+		"Ticket #%d. Status: %s. Valid for one entry, the code is checked at the gate. Do not share this ticket.",
+		e.Ticket.ID, e.Ticket.Status,
+	), "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+	return pdf.Error()
+}
+
+func formatVenue(v queries.Venue) string {
+	parts := []string{v.Name}
+	for _, part := range []string{v.Address, v.City} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatDate(date int, loc *time.Location) string {
+	if date == 0 {
+		return "Date to be announced"
+	}
+	return time.Unix(int64(date), 0).In(loc).Format("Monday, 02 January 2006 15:04 MST")
+}
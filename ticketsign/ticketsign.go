@@ -0,0 +1,102 @@
+// Package ticketsign signs ticket tokens and verifies them without a connection to the server.
+//
+// A token is base64url(json claims) + "." + base64url(ed25519 signature over the encoded claims).
+// Gate scanners only need the public key to verify it.
+package ticketsign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed ticket token")
+	ErrInvalidSignature = errors.New("invalid ticket token signature")
+	ErrTokenNotYetValid = errors.New("ticket token is not valid yet")
+	ErrTokenExpired     = errors.New("ticket token expired")
+)
+
+type Claims struct {
+	SerialNumber     string `json:"sn"`
+	ConcertID        int    `json:"cid"`
+	TicketCategoryID int    `json:"tcid"`
+	// Unix seconds, zero leaves that side of the window open.
+	NotBefore int64 `json:"nbf,omitempty"`
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key}
+}
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+func (s *Signer) Sign(c Claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	sig := ed25519.Sign(s.key, []byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+type Verifier struct {
+	key ed25519.PublicKey
+}
+
+func NewVerifier(key ed25519.PublicKey) *Verifier {
+	return &Verifier{key: key}
+}
+
+// Reads a public key as served by the gate-keeper API (standard base64).
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 public key size")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Verify checks the signature and the validity window at now and returns the claims.
+func (v *Verifier) Verify(token string, now time.Time) (Claims, error) {
+	var c Claims
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, ErrMalformedToken
+	}
+	rawSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return c, ErrMalformedToken
+	}
+	if !ed25519.Verify(v.key, []byte(encoded), rawSig) {
+		return c, ErrInvalidSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrMalformedToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, ErrMalformedToken
+	}
+	if c.NotBefore > 0 && now.Unix() < c.NotBefore {
+		return c, ErrTokenNotYetValid
+	}
+	if c.ExpiresAt > 0 && now.Unix() >= c.ExpiresAt {
+		return c, ErrTokenExpired
+	}
+	return c, nil
+}
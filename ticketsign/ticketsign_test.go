@@ -0,0 +1,128 @@
+package ticketsign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner(newKey(t))
+	want := Claims{
+		SerialNumber:     "GK-0001",
+		ConcertID:        7,
+		TicketCategoryID: 3,
+	}
+	token, err := signer.Sign(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewVerifier(signer.PublicKey()).Verify(token, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != want {
+		t.Errorf("claims = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	signer := NewSigner(newKey(t))
+	token, err := signer.Sign(Claims{SerialNumber: "GK-0001", ConcertID: 7, TicketCategoryID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, sig, _ := strings.Cut(token, ".")
+	// Same signature over a payload naming another concert.
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sn":"GK-0001","cid":8,"tcid":3}`))
+	tests := []struct {
+		name     string
+		token    string
+		verifier *Verifier
+		err      error
+	}{
+		{
+			name:     "tampered payload",
+			token:    forged + "." + sig,
+			verifier: NewVerifier(signer.PublicKey()),
+			err:      ErrInvalidSignature,
+		},
+		{
+			name:     "wrong key",
+			token:    token,
+			verifier: NewVerifier(NewSigner(newKey(t)).PublicKey()),
+			err:      ErrInvalidSignature,
+		},
+		{
+			name:     "missing signature",
+			token:    encoded,
+			verifier: NewVerifier(signer.PublicKey()),
+			err:      ErrMalformedToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.verifier.Verify(tt.token, time.Now()); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyWindow(t *testing.T) {
+	signer := NewSigner(newKey(t))
+	verifier := NewVerifier(signer.PublicKey())
+	date := time.Date(2026, 12, 31, 19, 0, 0, 0, time.UTC)
+	token, err := signer.Sign(Claims{
+		SerialNumber: "GK-0001",
+		ConcertID:    7,
+		NotBefore:    date.Add(-time.Hour).Unix(),
+		ExpiresAt:    date.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		err  error
+	}{
+		{name: "not yet valid", now: date.Add(-time.Hour - time.Second), err: ErrTokenNotYetValid},
+		{name: "first valid second", now: date.Add(-time.Hour)},
+		{name: "during the concert", now: date},
+		{name: "expired", now: date.Add(time.Hour), err: ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(token, tt.now); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	want := NewSigner(newKey(t)).PublicKey()
+	got, err := ParsePublicKey(base64.StdEncoding.EncodeToString(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("key = %x, want %x", got, want)
+	}
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString(want[:16])); err == nil {
+		t.Error("short key was accepted")
+	}
+}
@@ -0,0 +1,293 @@
+package waitroom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrRoomClosed   = errors.New("waiting room is not open for this concert")
+	ErrUnknownToken = errors.New("queue token not found")
+)
+
+// Room settings for a single concert.
+type Room struct {
+	ConcertID int  `json:"concert_id"`
+	Open      bool `json:"open"`
+	Paused    bool `json:"paused"`
+	// Number of users admitted on every tick.
+	BatchSize int `json:"batch_size"`
+	// How long an admitted user may buy before the admission expires (seconds).
+	AdmissionTTL int `json:"admission_ttl"`
+	Waiting      int `json:"waiting"`
+}
+
+type Position struct {
+	Token    string `json:"token"`
+	Position int    `json:"position,omitempty"`
+	Admitted bool   `json:"admitted"`
+}
+
+// WaitRoom is a FIFO queue in front of ticket purchases, backed by Redis.
+//
+// Per concert it keeps:
+//   - a hash with the room settings,
+//   - a sorted set of waiting tokens scored by arrival sequence,
+//   - one key per admitted token that expires after the admission TTL.
+type WaitRoom struct {
+	redis *redisClient.Client
+	log   *slog.Logger
+}
+
+func NewWaitRoom(redis *redisClient.Client, log *slog.Logger) *WaitRoom {
+	return &WaitRoom{
+		redis: redis,
+		log:   log,
+	}
+}
+
+const roomsKey = "waitroom:concerts"
+
+// Held by the replica admitting the current tick, so batches are not admitted once per replica.
+const admitLockKey = "waitroom:admit:lock"
+
+func roomKey(concertID int) string {
+	return fmt.Sprintf("waitroom:concert:%d", concertID)
+}
+
+func queueKey(concertID int) string {
+	return fmt.Sprintf("waitroom:concert:%d:queue", concertID)
+}
+
+func seqKey(concertID int) string {
+	return fmt.Sprintf("waitroom:concert:%d:seq", concertID)
+}
+
+func admittedKey(concertID int, token string) string {
+	return fmt.Sprintf("waitroom:concert:%d:admitted:%s", concertID, token)
+}
+
+// Opens (or reconfigures) the room of a concert.
+func (w *WaitRoom) Open(ctx context.Context, concertID int, batchSize int, admissionTTL time.Duration) (Room, error) {
+	_, err := w.redis.TxPipelined(ctx, func(p redisClient.Pipeliner) error {
+		p.HSet(ctx, roomKey(concertID),
+			"open", true,
+			"paused", false,
+			"batch_size", batchSize,
+			"admission_ttl", int(admissionTTL.Seconds()),
+		)
+		p.SAdd(ctx, roomsKey, concertID)
+		return nil
+	})
+	if err != nil {
+		return Room{}, err
+	}
+	return w.Room(ctx, concertID)
+}
+
+func (w *WaitRoom) Room(ctx context.Context, concertID int) (Room, error) {
+	r := Room{ConcertID: concertID}
+	values, err := w.redis.HGetAll(ctx, roomKey(concertID)).Result()
+	if err != nil {
+		return r, err
+	}
+	if len(values) == 0 {
+		return r, nil
+	}
+	r.Open, _ = strconv.ParseBool(values["open"])
+	r.Paused, _ = strconv.ParseBool(values["paused"])
+	r.BatchSize, _ = strconv.Atoi(values["batch_size"])
+	r.AdmissionTTL, _ = strconv.Atoi(values["admission_ttl"])
+	waiting, err := w.redis.ZCard(ctx, queueKey(concertID)).Result()
+	if err != nil {
+		return r, err
+	}
+	r.Waiting = int(waiting)
+	return r, nil
+}
+
+func (w *WaitRoom) Pause(ctx context.Context, concertID int) (Room, error) {
+	return w.setPaused(ctx, concertID, true)
+}
+
+func (w *WaitRoom) Resume(ctx context.Context, concertID int) (Room, error) {
+	return w.setPaused(ctx, concertID, false)
+}
+
+func (w *WaitRoom) setPaused(ctx context.Context, concertID int, paused bool) (Room, error) {
+	r, err := w.Room(ctx, concertID)
+	if err != nil {
+		return r, err
+	}
+	if !r.Open {
+		return r, ErrRoomClosed
+	}
+	if err := w.redis.HSet(ctx, roomKey(concertID), "paused", paused).Err(); err != nil {
+		return r, err
+	}
+	r.Paused = paused
+	return r, nil
+}
+
+// Drain closes the room and drops everyone still waiting.
+// Users that were already admitted keep their admission until it expires.
+func (w *WaitRoom) Drain(ctx context.Context, concertID int) (int, error) {
+	var dropped *redisClient.IntCmd
+	_, err := w.redis.TxPipelined(ctx, func(p redisClient.Pipeliner) error {
+		dropped = p.ZCard(ctx, queueKey(concertID))
+		p.Del(ctx, queueKey(concertID), roomKey(concertID))
+		p.SRem(ctx, roomsKey, concertID)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(dropped.Val()), nil
+}
+
+// Join puts a new user at the back of the line.
+func (w *WaitRoom) Join(ctx context.Context, concertID int) (Position, error) {
+	r, err := w.Room(ctx, concertID)
+	if err != nil {
+		return Position{}, err
+	}
+	if !r.Open {
+		return Position{}, ErrRoomClosed
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Position{}, err
+	}
+	token := hex.EncodeToString(b)
+	seq, err := w.redis.Incr(ctx, seqKey(concertID)).Result()
+	if err != nil {
+		return Position{}, err
+	}
+	if err := w.redis.ZAdd(ctx, queueKey(concertID), redisClient.Z{
+		Score:  float64(seq),
+		Member: token,
+	}).Err(); err != nil {
+		return Position{}, err
+	}
+	return w.Position(ctx, concertID, token)
+}
+
+func (w *WaitRoom) Position(ctx context.Context, concertID int, token string) (Position, error) {
+	p := Position{Token: token}
+	admitted, err := w.IsAdmitted(ctx, concertID, token)
+	if err != nil {
+		return p, err
+	}
+	if admitted {
+		p.Admitted = true
+		return p, nil
+	}
+	rank, err := w.redis.ZRank(ctx, queueKey(concertID), token).Result()
+	if err != nil {
+		if errors.Is(err, redisClient.Nil) {
+			return p, ErrUnknownToken
+		}
+		return p, err
+	}
+	p.Position = int(rank) + 1
+	return p, nil
+}
+
+func (w *WaitRoom) IsAdmitted(ctx context.Context, concertID int, token string) (bool, error) {
+	n, err := w.redis.Exists(ctx, admittedKey(concertID, token)).Result()
+	return n > 0, err
+}
+
+// Consume ends the admission of the token and reports whether it was admitted.
+// Checking and ending happen in one step, so concurrent requests cannot share an admission
+// and the same token cannot buy again without queueing.
+func (w *WaitRoom) Consume(ctx context.Context, concertID int, token string) (bool, error) {
+	err := w.redis.GetDel(ctx, admittedKey(concertID, token)).Err()
+	if errors.Is(err, redisClient.Nil) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Reports whether purchases for the concert have to go through the queue.
+func (w *WaitRoom) IsGuarded(ctx context.Context, concertID int) (bool, error) {
+	return w.redis.SIsMember(ctx, roomsKey, concertID).Result()
+}
+
+// Admit lets the next batch of users in for every open, non-paused room.
+func (w *WaitRoom) Admit(ctx context.Context) error {
+	concerts, err := w.redis.SMembers(ctx, roomsKey).Result()
+	if err != nil {
+		return err
+	}
+	for _, c := range concerts {
+		concertID, err := strconv.Atoi(c)
+		if err != nil {
+			continue
+		}
+		if err := w.admitConcert(ctx, concertID); err != nil {
+			w.log.Error(err.Error(), slog.Int("concert_id", concertID))
+		}
+	}
+	return nil
+}
+
+func (w *WaitRoom) admitConcert(ctx context.Context, concertID int) error {
+	r, err := w.Room(ctx, concertID)
+	if err != nil {
+		return err
+	}
+	if !r.Open || r.Paused || r.BatchSize <= 0 || r.Waiting == 0 {
+		return nil
+	}
+	popped, err := w.redis.ZPopMin(ctx, queueKey(concertID), int64(r.BatchSize)).Result()
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(r.AdmissionTTL) * time.Second
+	_, err = w.redis.Pipelined(ctx, func(p redisClient.Pipeliner) error {
+		for _, z := range popped {
+			p.Set(ctx, admittedKey(concertID, z.Member.(string)), 1, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	w.log.Info("waiting room batch admitted", slog.Int("concert_id", concertID), slog.Int("admitted", len(popped)))
+	return nil
+}
+
+// Run admits a batch every interval until ctx is cancelled.
+// Every replica runs it, only the one that takes the admit lock for the interval admits.
+func (w *WaitRoom) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// The lock expires on its own, right before the next tick.
+			acquired, err := w.redis.SetNX(ctx, admitLockKey, 1, interval-interval/10).Result()
+			if err != nil {
+				w.log.Error(err.Error())
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := w.Admit(ctx); err != nil {
+				w.log.Error(err.Error())
+			}
+		}
+	}
+}